@@ -0,0 +1,51 @@
+// Command openapi-gen generates route registrations and Go types from an OpenAPI 3 document
+// (JSON or YAML):
+//
+//	go run github.com/loissascha/go-http-server/cmd/openapi-gen -i openapi.yaml -o api/api.gen.go -package api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/loissascha/go-http-server/internal/openapidoc"
+	"github.com/loissascha/go-http-server/internal/openapigen"
+)
+
+func main() {
+	input := flag.String("i", "openapi.yaml", "OpenAPI document (JSON or YAML)")
+	output := flag.String("o", "", "generated Go file (default stdout)")
+	pkg := flag.String("package", "api", "package of the generated file")
+	flag.Parse()
+
+	err := run(*input, *output, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "openapi-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string, output string, pkg string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	doc, err := openapidoc.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	src, err := openapigen.Generate(doc, openapigen.Config{
+		Package: pkg,
+		Source:  filepath.Base(input),
+	})
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(output, src, 0644)
+}
@@ -0,0 +1,286 @@
+// Package openapidoc reads OpenAPI documents (JSON or YAML) into generic JSON values. It is
+// shared by the mock routes of the server and the openapi-gen code generator.
+package openapidoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTP methods of a path item in the order they are processed
+var Methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Parse a JSON or YAML document. Objects are map[string]any, arrays []any and numbers
+// float64, as with encoding/json.
+func Parse(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err := json.Unmarshal(trimmed, &doc)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var node yaml.Node
+		err := yaml.Unmarshal(data, &node)
+		if err != nil {
+			return nil, err
+		}
+		value, err := fromYAML(&node)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		doc, ok = value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("openapi: document is %T, not an object", value)
+		}
+	}
+
+	version, _ := doc["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("openapi: unsupported version %q", version)
+	}
+	return doc, nil
+}
+
+// JSON value of a YAML node (timestamps stay strings)
+func fromYAML(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return fromYAML(node.Content[0])
+	case yaml.AliasNode:
+		return fromYAML(node.Alias)
+	case yaml.MappingNode:
+		result := map[string]any{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := fromYAML(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			result[node.Content[i].Value] = value
+		}
+		return result, nil
+	case yaml.SequenceNode:
+		result := []any{}
+		for _, child := range node.Content {
+			value, err := fromYAML(child)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	case "!!int", "!!float":
+		var f float64
+		err := node.Decode(&f)
+		return f, err
+	}
+	return node.Value, nil
+}
+
+func Object(v any) map[string]any {
+	object, _ := v.(map[string]any)
+	return object
+}
+
+func Array(v any) []any {
+	array, _ := v.([]any)
+	return array
+}
+
+// Sorted keys of an object
+func Keys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Follow local $refs like "#/components/schemas/User", nil if a ref can't be resolved
+func Resolve(doc map[string]any, value map[string]any) map[string]any {
+	for range 32 {
+		ref, ok := value["$ref"].(string)
+		if !ok {
+			return value
+		}
+		value = Object(Pointer(doc, ref))
+	}
+	return value
+}
+
+// Value of a local JSON pointer like "#/components/schemas/User"
+func Pointer(doc map[string]any, ref string) any {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var current any = doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		current = Object(current)[token]
+	}
+	return current
+}
+
+// Name of a "#/components/schemas/<name>" ref, "" for other refs
+func SchemaRefName(schema map[string]any) string {
+	ref, _ := schema["$ref"].(string)
+	name, found := strings.CutPrefix(ref, "#/components/schemas/")
+	if !found || strings.Contains(name, "/") {
+		return ""
+	}
+	return name
+}
+
+// Copy of schema with all $refs replaced by their targets (recursive refs end as empty
+// schemas after depth levels)
+func Inline(doc map[string]any, schema map[string]any, depth int) map[string]any {
+	schema = Resolve(doc, schema)
+	if schema == nil || depth <= 0 {
+		return map[string]any{}
+	}
+	result := map[string]any{}
+	for key, value := range schema {
+		result[key] = inlineValue(doc, value, depth-1)
+	}
+	return result
+}
+
+func inlineValue(doc map[string]any, value any, depth int) any {
+	switch value := value.(type) {
+	case map[string]any:
+		return Inline(doc, value, depth)
+	case []any:
+		result := []any{}
+		for _, item := range value {
+			result = append(result, inlineValue(doc, item, depth))
+		}
+		return result
+	}
+	return value
+}
+
+// Types of a schema without "null", "type" can be a string or a list. The second result
+// reports if null is allowed (in the type list or with the 3.0 nullable keyword).
+func Types(schema map[string]any) ([]string, bool) {
+	types := []string{}
+	nullable, _ := schema["nullable"].(bool)
+	switch t := schema["type"].(type) {
+	case string:
+		types = append(types, t)
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if i := slices.Index(types, "null"); i >= 0 {
+		types = slices.Delete(types, i, i+1)
+		nullable = true
+	}
+	return types, nullable
+}
+
+// Example value of a schema: its example, default, const or first enum value, otherwise
+// one built from the type
+func Example(doc map[string]any, schema map[string]any) any {
+	return example(doc, schema, 0)
+}
+
+func example(doc map[string]any, schema map[string]any, depth int) any {
+	schema = Resolve(doc, schema)
+	if schema == nil || depth > 8 {
+		return nil
+	}
+	for _, key := range []string{"example", "default", "const"} {
+		if value, found := schema[key]; found {
+			return value
+		}
+	}
+	for _, key := range []string{"examples", "enum"} {
+		if values := Array(schema[key]); len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	if all := Array(schema["allOf"]); len(all) > 0 {
+		result := map[string]any{}
+		for _, sub := range all {
+			for key, value := range Object(example(doc, Object(sub), depth+1)) {
+				result[key] = value
+			}
+		}
+		return result
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		for _, sub := range Array(schema[key]) {
+			if Object(sub)["type"] == "null" {
+				continue
+			}
+			return example(doc, Object(sub), depth+1)
+		}
+	}
+
+	types, _ := Types(schema)
+	t := ""
+	if len(types) > 0 {
+		t = types[0]
+	} else if schema["properties"] != nil {
+		t = "object"
+	}
+	switch t {
+	case "object":
+		result := map[string]any{}
+		properties := Object(schema["properties"])
+		for _, name := range Keys(properties) {
+			result[name] = example(doc, Object(properties[name]), depth+1)
+		}
+		return result
+	case "array":
+		if items := Object(schema["items"]); items != nil {
+			return []any{example(doc, items, depth+1)}
+		}
+		return []any{}
+	case "string":
+		switch schema["format"] {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "uri":
+			return "https://example.com"
+		}
+		return "string"
+	case "integer", "number":
+		if minimum, ok := schema["minimum"].(float64); ok {
+			return minimum
+		}
+		return float64(0)
+	case "boolean":
+		return true
+	}
+	return nil
+}
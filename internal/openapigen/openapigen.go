@@ -0,0 +1,674 @@
+// Package openapigen generates route registrations and Go types for the server package from
+// an OpenAPI document. It is the implementation of cmd/openapi-gen.
+package openapigen
+
+import (
+	"fmt"
+	"go/format"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/loissascha/go-http-server/internal/openapidoc"
+)
+
+type Config struct {
+	// package of the generated file
+	Package string
+	// file name of the document, mentioned in the header
+	Source string
+}
+
+// Generate the Go source of a document parsed with openapidoc.Parse
+func Generate(doc map[string]any, config Config) ([]byte, error) {
+	g := &generator{
+		doc:     doc,
+		names:   map[string]bool{},
+		refs:    map[string]string{},
+		structs: map[string]bool{},
+		imports: map[string]bool{"net/http": true, "github.com/loissascha/go-http-server/server": true},
+	}
+
+	schemas := openapidoc.Object(openapidoc.Object(doc["components"])["schemas"])
+	for _, name := range openapidoc.Keys(schemas) {
+		g.refs[name] = g.typeName(goName(name))
+		schema := openapidoc.Object(schemas[name])
+		if isObjectSchema(schema) || len(openapidoc.Array(schema["allOf"])) > 1 {
+			g.structs[g.refs[name]] = true
+		}
+	}
+	for _, name := range openapidoc.Keys(schemas) {
+		g.declare(g.refs[name], openapidoc.Object(schemas[name]))
+	}
+	operations := g.operations()
+
+	var b strings.Builder
+	source := ""
+	if config.Source != "" {
+		source = " from " + config.Source
+	}
+	fmt.Fprintf(&b, "// Code generated by openapi-gen%s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&b, "package %s\n\n", config.Package)
+	b.WriteString("import (\n")
+	for _, path := range slices.Sorted(maps.Keys(g.imports)) {
+		if !strings.Contains(path, ".") {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+	}
+	b.WriteString("\n")
+	for _, path := range slices.Sorted(maps.Keys(g.imports)) {
+		if strings.Contains(path, ".") {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+	}
+	b.WriteString(")\n\n")
+	for _, decl := range g.decls {
+		b.WriteString(decl)
+		b.WriteString("\n")
+	}
+	g.writeHandlers(&b, operations)
+	g.writeServerOptions(&b)
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("openapigen: formatting generated code: %w\n%s", err, b.String())
+	}
+	return src, nil
+}
+
+type generator struct {
+	doc map[string]any
+	// type declarations in the order they were created
+	decls []string
+	names map[string]bool
+	// component schema name to Go type name
+	refs map[string]string
+	// Go types declared as structs
+	structs map[string]bool
+	imports map[string]bool
+}
+
+type operation struct {
+	Name      string
+	Method    string
+	Path      string
+	Route     string
+	Operation map[string]any
+	Params    []map[string]any
+	Security  []any
+	Request   string
+	Responses []response
+}
+
+type response struct {
+	Code        string
+	Description string
+	Type        string
+}
+
+// unique Go type name
+func (g *generator) typeName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+func comment(name string, text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	lines[0] = name + " " + lines[0]
+	if name == "" {
+		lines[0] = strings.TrimSpace(lines[0])
+	}
+	return "// " + strings.Join(lines, "\n// ") + "\n"
+}
+
+// declares a named type for a schema
+func (g *generator) declare(name string, schema map[string]any) {
+	description, _ := schema["description"].(string)
+	doc := comment(name, description)
+
+	if ref := openapidoc.SchemaRefName(schema); ref != "" {
+		g.decls = append(g.decls, fmt.Sprintf("%stype %s = %s\n", doc, name, g.typeExpr(schema, name)))
+		return
+	}
+
+	types, _ := openapidoc.Types(schema)
+	enum := openapidoc.Array(schema["enum"])
+	if len(types) == 1 && types[0] == "string" && len(enum) > 0 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%stype %s string\n\nconst (\n", doc, name)
+		used := map[string]bool{}
+		for _, value := range enum {
+			value, ok := value.(string)
+			if !ok {
+				continue
+			}
+			constName := name + goName(value)
+			if used[constName] {
+				continue
+			}
+			used[constName] = true
+			fmt.Fprintf(&b, "\t%s %s = %q\n", constName, name, value)
+		}
+		b.WriteString(")\n")
+		g.decls = append(g.decls, b.String())
+		return
+	}
+
+	if isObjectSchema(schema) || len(openapidoc.Array(schema["allOf"])) > 1 {
+		g.structs[name] = true
+		// nested types are declared while the fields are built and come after the struct
+		index := len(g.decls)
+		g.decls = append(g.decls, "")
+		g.decls[index] = fmt.Sprintf("%stype %s struct {\n%s}\n", doc, name, g.structFields(name, schema))
+		return
+	}
+
+	g.decls = append(g.decls, fmt.Sprintf("%stype %s %s\n", doc, name, g.typeExpr(schema, name)))
+}
+
+func isObjectSchema(schema map[string]any) bool {
+	types, _ := openapidoc.Types(schema)
+	if len(openapidoc.Object(schema["properties"])) > 0 {
+		return true
+	}
+	return slices.Equal(types, []string{"object"}) && schema["additionalProperties"] == nil
+}
+
+// fields of a struct, allOf members that are refs are embedded
+func (g *generator) structFields(name string, schema map[string]any) string {
+	var b strings.Builder
+	for _, member := range openapidoc.Array(schema["allOf"]) {
+		member := openapidoc.Object(member)
+		if ref := openapidoc.SchemaRefName(member); ref != "" && g.refs[ref] != "" {
+			fmt.Fprintf(&b, "\t%s\n", g.refs[ref])
+			continue
+		}
+		b.WriteString(g.structFields(name, openapidoc.Resolve(g.doc, member)))
+	}
+
+	required := map[string]bool{}
+	for _, field := range openapidoc.Array(schema["required"]) {
+		if field, ok := field.(string); ok {
+			required[field] = true
+		}
+	}
+	properties := openapidoc.Object(schema["properties"])
+	fieldNames := map[string]bool{}
+	for _, property := range openapidoc.Keys(properties) {
+		propertySchema := openapidoc.Object(properties[property])
+		fieldName := goName(property)
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", goName(property), i)
+		}
+		fieldNames[fieldName] = true
+
+		fieldType := g.typeExpr(propertySchema, name+fieldName)
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+			// omitempty doesn't leave out structs
+			if g.structs[fieldType] || fieldType == "time.Time" {
+				fieldType = "*" + fieldType
+			}
+		}
+		if fieldType == name {
+			fieldType = "*" + fieldType
+		}
+		description, _ := propertySchema["description"].(string)
+		b.WriteString(indent(comment("", description)))
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", fieldName, fieldType, tag)
+	}
+	return b.String()
+}
+
+func indent(s string) string {
+	if s == "" {
+		return s
+	}
+	return "\t" + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n\t") + "\n"
+}
+
+// Go type of a schema, inline objects are declared as types named context
+func (g *generator) typeExpr(schema map[string]any, context string) string {
+	if ref := openapidoc.SchemaRefName(schema); ref != "" {
+		if name, found := g.refs[ref]; found {
+			return name
+		}
+		return "any"
+	}
+	schema = openapidoc.Resolve(g.doc, schema)
+	if schema == nil {
+		return "any"
+	}
+
+	if all := openapidoc.Array(schema["allOf"]); len(all) == 1 {
+		return g.typeExpr(openapidoc.Object(all[0]), context)
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		alternatives := openapidoc.Array(schema[keyword])
+		if len(alternatives) == 0 {
+			continue
+		}
+		nonNull := []map[string]any{}
+		for _, alternative := range alternatives {
+			if openapidoc.Object(alternative)["type"] != "null" {
+				nonNull = append(nonNull, openapidoc.Object(alternative))
+			}
+		}
+		if len(nonNull) != 1 {
+			return "any"
+		}
+		return nullableType(g.typeExpr(nonNull[0], context), len(nonNull) < len(alternatives))
+	}
+
+	if isObjectSchema(schema) || len(openapidoc.Array(schema["allOf"])) > 1 {
+		name := g.typeName(context)
+		g.declare(name, schema)
+		_, nullable := openapidoc.Types(schema)
+		return nullableType(name, nullable)
+	}
+
+	types, nullable := openapidoc.Types(schema)
+	if len(types) != 1 {
+		return "any"
+	}
+	format, _ := schema["format"].(string)
+	t := "any"
+	switch types[0] {
+	case "string":
+		t = "string"
+		switch format {
+		case "date-time":
+			g.imports["time"] = true
+			t = "time.Time"
+		case "byte":
+			t = "[]byte"
+		}
+	case "integer":
+		t = "int"
+		if format == "int32" || format == "int64" {
+			t = format
+		}
+	case "number":
+		t = "float64"
+		if format == "float" {
+			t = "float32"
+		}
+	case "boolean":
+		t = "bool"
+	case "array":
+		t = "[]" + g.typeExpr(openapidoc.Object(schema["items"]), context+"Item")
+	case "object":
+		t = "map[string]any"
+		if additional := openapidoc.Object(schema["additionalProperties"]); additional != nil {
+			t = "map[string]" + g.typeExpr(additional, context+"Value")
+		}
+	}
+	return nullableType(t, nullable)
+}
+
+func nullableType(t string, nullable bool) string {
+	if !nullable || t == "any" || strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[") || strings.HasPrefix(t, "*") {
+		return t
+	}
+	return "*" + t
+}
+
+var routeSegment = regexp.MustCompile(`^(\{[A-Za-z_][A-Za-z0-9_]*\}|[^{}]*)$`)
+
+// ServeMux pattern of an OpenAPI path (see MockOpenAPI of the server)
+func routePattern(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return "", false
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if !routeSegment.MatchString(segment) {
+			return "", false
+		}
+	}
+	if strings.HasSuffix(path, "/") {
+		path += "{$}"
+	}
+	return path, true
+}
+
+// all operations of the document, sorted by path and method
+func (g *generator) operations() []operation {
+	result := []operation{}
+	operationNames := map[string]bool{"": true}
+	paths := openapidoc.Object(g.doc["paths"])
+	for _, path := range openapidoc.Keys(paths) {
+		item := openapidoc.Resolve(g.doc, openapidoc.Object(paths[path]))
+		for _, method := range openapidoc.Methods {
+			op := openapidoc.Object(item[method])
+			if op == nil {
+				continue
+			}
+			o := operation{Method: strings.ToUpper(method), Path: path, Operation: op}
+
+			operationId, _ := op["operationId"].(string)
+			if operationId == "" {
+				operationId = method + " " + strings.NewReplacer("{", "by ", "}", "").Replace(path)
+			}
+			name := goName(operationId)
+			o.Name = name
+			for i := 2; operationNames[o.Name]; i++ {
+				o.Name = fmt.Sprintf("%s%d", name, i)
+			}
+			operationNames[o.Name] = true
+
+			o.Route, _ = routePattern(path)
+			for _, raw := range append(openapidoc.Array(item["parameters"]), openapidoc.Array(op["parameters"])...) {
+				param := openapidoc.Resolve(g.doc, openapidoc.Object(raw))
+				o.Params = slices.DeleteFunc(o.Params, func(existing map[string]any) bool {
+					return existing["name"] == param["name"] && existing["in"] == param["in"]
+				})
+				o.Params = append(o.Params, param)
+			}
+			if security, found := op["security"]; found {
+				o.Security = openapidoc.Array(security)
+			} else {
+				o.Security = openapidoc.Array(g.doc["security"])
+			}
+
+			requestBody := openapidoc.Resolve(g.doc, openapidoc.Object(op["requestBody"]))
+			if media := openapidoc.Object(openapidoc.Object(requestBody["content"])["application/json"]); media != nil {
+				o.Request = g.typeExpr(openapidoc.Object(media["schema"]), o.Name+"Request")
+			}
+
+			responses := openapidoc.Object(op["responses"])
+			for _, code := range openapidoc.Keys(responses) {
+				if _, err := strconv.Atoi(code); err != nil {
+					continue
+				}
+				r := openapidoc.Resolve(g.doc, openapidoc.Object(responses[code]))
+				resp := response{Code: code}
+				resp.Description, _ = r["description"].(string)
+				if media := openapidoc.Object(openapidoc.Object(r["content"])["application/json"]); media != nil && media["schema"] != nil {
+					context := o.Name + "Response"
+					if !strings.HasPrefix(code, "2") {
+						context += code
+					}
+					resp.Type = g.typeExpr(openapidoc.Object(media["schema"]), context)
+				}
+				o.Responses = append(o.Responses, resp)
+			}
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// methods with a registration function of the server (s.GET, ...)
+var registerMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+func (g *generator) writeHandlers(b *strings.Builder, operations []operation) {
+	b.WriteString("// Handlers of the operations. Operations without handler aren't registered, MockOpenAPI\n")
+	b.WriteString("// of the server can answer them with examples until they are implemented.\n")
+	b.WriteString("type Handlers struct {\n")
+	for _, o := range operations {
+		summary, _ := o.Operation["summary"].(string)
+		fmt.Fprintf(b, "\t// %s %s", o.Method, o.Path)
+		if summary != "" {
+			fmt.Fprintf(b, ": %s", strings.ReplaceAll(summary, "\n", " "))
+		}
+		fmt.Fprintf(b, "\n\t%s func(w http.ResponseWriter, r *http.Request)\n", o.Name)
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("// Register the routes of the handlers that are set\n")
+	b.WriteString("func RegisterRoutes(s *server.Server, h Handlers) {\n")
+	for _, o := range operations {
+		if o.Route == "" {
+			fmt.Fprintf(b, "\t// %s %s: not a valid route pattern\n", o.Method, o.Path)
+			continue
+		}
+		fmt.Fprintf(b, "\tif h.%s != nil {\n", o.Name)
+		if slices.Contains(registerMethods, o.Method) {
+			fmt.Fprintf(b, "\t\ts.%s(%q, h.%s,\n", o.Method, o.Route, o.Name)
+		} else {
+			fmt.Fprintf(b, "\t\ts.Route(%q, %q, h.%s,\n", o.Method, o.Route, o.Name)
+		}
+		for _, option := range g.routeOptions(o) {
+			fmt.Fprintf(b, "\t\t\t%s,\n", option)
+		}
+		b.WriteString("\t\t)\n\t}\n")
+	}
+	b.WriteString("}\n\n")
+}
+
+func (g *generator) routeOptions(o operation) []string {
+	options := []string{}
+	if id, _ := o.Operation["operationId"].(string); id != "" {
+		options = append(options, fmt.Sprintf("server.WithOperationId(%q)", id))
+	}
+	if summary, _ := o.Operation["summary"].(string); summary != "" {
+		options = append(options, fmt.Sprintf("server.WithSummary(%q)", summary))
+	}
+	if description, _ := o.Operation["description"].(string); description != "" {
+		options = append(options, fmt.Sprintf("server.WithDescription(%q)", description))
+	}
+	if tags := openapidoc.Array(o.Operation["tags"]); len(tags) > 0 {
+		quoted := []string{}
+		for _, tag := range tags {
+			quoted = append(quoted, goLiteral(tag))
+		}
+		options = append(options, fmt.Sprintf("server.WithTags(%s)", strings.Join(quoted, ", ")))
+	}
+
+	if len(o.Params) > 0 {
+		params := []string{}
+		for _, param := range o.Params {
+			fields := []string{}
+			for _, key := range []string{"name", "in", "description"} {
+				if value, _ := param[key].(string); value != "" {
+					fields = append(fields, fmt.Sprintf("%s: %q", map[string]string{"name": "Name", "in": "In", "description": "Description"}[key], value))
+				}
+			}
+			if required, _ := param["required"].(bool); required {
+				fields = append(fields, "Required: true")
+			}
+			if schema := openapidoc.Object(param["schema"]); schema != nil {
+				fields = append(fields, "Schema: "+goLiteral(openapidoc.Inline(g.doc, schema, 8)))
+			}
+			params = append(params, "server.OpenAPIParam{"+strings.Join(fields, ", ")+"}")
+		}
+		options = append(options, "server.WithParams(\n\t\t\t\t"+strings.Join(params, ",\n\t\t\t\t")+",\n\t\t\t)")
+	}
+
+	// single schemes with WithSecurity, combined schemes and anonymous access ({}) with
+	// WithSecurityRequirement
+	for _, requirement := range o.Security {
+		requirement := openapidoc.Object(requirement)
+		if len(requirement) == 1 {
+			name := openapidoc.Keys(requirement)[0]
+			args := []string{strconv.Quote(name)}
+			for _, scope := range openapidoc.Array(requirement[name]) {
+				args = append(args, goLiteral(scope))
+			}
+			options = append(options, fmt.Sprintf("server.WithSecurity(%s)", strings.Join(args, ", ")))
+			continue
+		}
+		schemes := []string{}
+		for _, name := range openapidoc.Keys(requirement) {
+			scopes := []string{}
+			for _, scope := range openapidoc.Array(requirement[name]) {
+				scopes = append(scopes, goLiteral(scope))
+			}
+			schemes = append(schemes, fmt.Sprintf("%q: {%s}", name, strings.Join(scopes, ", ")))
+		}
+		options = append(options, fmt.Sprintf("server.WithSecurityRequirement(map[string][]string{%s})", strings.Join(schemes, ", ")))
+	}
+
+	if o.Request != "" && o.Request != "any" {
+		options = append(options, fmt.Sprintf("server.WithRequestBody[%s]()", o.Request))
+	}
+	for _, r := range o.Responses {
+		options = append(options, fmt.Sprintf("server.WithResponse(%s, server.OpenAPIResponse{Description: %q})", r.Code, r.Description))
+		if r.Type != "" && r.Type != "any" {
+			options = append(options, fmt.Sprintf("server.WithResponseType[%s](%s)", r.Type, r.Code))
+		}
+	}
+	return options
+}
+
+func (g *generator) writeServerOptions(b *strings.Builder) {
+	info := openapidoc.Object(g.doc["info"])
+	b.WriteString("// Info, servers and security schemes of the document\n")
+	b.WriteString("func ServerOptions() []server.ServerOption {\n\treturn []server.ServerOption{\n")
+	b.WriteString("\t\tserver.WithOpenAPIInfo(server.OpenAPIInfo{\n")
+	for _, key := range []string{"title", "version", "description"} {
+		if value, _ := info[key].(string); value != "" {
+			fmt.Fprintf(b, "\t\t\t%s: %q,\n", exportedKey(key), value)
+		}
+	}
+	if contact := openapidoc.Object(info["contact"]); contact != nil {
+		fmt.Fprintf(b, "\t\t\tContact: &server.OpenAPIContact{%s},\n", stringFields(contact, "name", "url", "email"))
+	}
+	if license := openapidoc.Object(info["license"]); license != nil {
+		fmt.Fprintf(b, "\t\t\tLicense: &server.OpenAPILicense{%s},\n", stringFields(license, "name", "identifier", "url"))
+	}
+	if servers := openapidoc.Array(g.doc["servers"]); len(servers) > 0 {
+		b.WriteString("\t\t\tServers: []server.OpenAPIServer{\n")
+		for _, s := range servers {
+			fmt.Fprintf(b, "\t\t\t\t{%s},\n", stringFields(openapidoc.Object(s), "url", "description"))
+		}
+		b.WriteString("\t\t\t},\n")
+	}
+	b.WriteString("\t\t}),\n")
+
+	schemes := openapidoc.Object(openapidoc.Object(g.doc["components"])["securitySchemes"])
+	for _, name := range openapidoc.Keys(schemes) {
+		scheme := openapidoc.Resolve(g.doc, openapidoc.Object(schemes[name]))
+		fields := stringFields(scheme, "type", "description", "scheme", "bearerFormat", "name", "in", "openIdConnectUrl")
+		if flows := openapidoc.Object(scheme["flows"]); flows != nil {
+			flowFields := []string{}
+			for _, flow := range []string{"implicit", "password", "clientCredentials", "authorizationCode"} {
+				if f := openapidoc.Object(flows[flow]); f != nil {
+					scopes := map[string]any{}
+					for scope, description := range openapidoc.Object(f["scopes"]) {
+						scopes[scope] = description
+					}
+					flowFields = append(flowFields, fmt.Sprintf("%s: &server.OAuthFlow{%s, Scopes: %s}",
+						exportedKey(flow), stringFields(f, "authorizationUrl", "tokenUrl", "refreshUrl"), stringMapLiteral(scopes)))
+				}
+			}
+			fields += ", Flows: &server.OAuthFlows{" + strings.Join(flowFields, ", ") + "}"
+		}
+		fmt.Fprintf(b, "\t\tserver.WithSecurityScheme(%q, server.SecurityScheme{%s}),\n", name, strings.TrimPrefix(fields, ", "))
+	}
+	b.WriteString("\t}\n}\n")
+}
+
+// struct literal fields of the string values of an object, the fields of the server types
+// are named like the keys (tokenUrl is TokenUrl)
+func stringFields(object map[string]any, keys ...string) string {
+	fields := []string{}
+	for _, key := range keys {
+		if value, _ := object[key].(string); value != "" {
+			fields = append(fields, fmt.Sprintf("%s: %q", exportedKey(key), value))
+		}
+	}
+	return strings.Join(fields, ", ")
+}
+
+func exportedKey(key string) string {
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+func stringMapLiteral(m map[string]any) string {
+	entries := []string{}
+	for _, key := range openapidoc.Keys(m) {
+		value, _ := m[key].(string)
+		entries = append(entries, fmt.Sprintf("%q: %q", key, value))
+	}
+	return "map[string]string{" + strings.Join(entries, ", ") + "}"
+}
+
+// Go literal of a JSON value
+func goLiteral(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		items := []string{}
+		for _, item := range v {
+			items = append(items, goLiteral(item))
+		}
+		return "[]any{" + strings.Join(items, ", ") + "}"
+	case map[string]any:
+		entries := []string{}
+		for _, key := range openapidoc.Keys(v) {
+			entries = append(entries, fmt.Sprintf("%q: %s", key, goLiteral(v[key])))
+		}
+		return "map[string]any{" + strings.Join(entries, ", ") + "}"
+	}
+	return "nil"
+}
+
+var initialisms = map[string]string{
+	"api": "API", "http": "HTTP", "id": "ID", "ip": "IP", "json": "JSON", "jwt": "JWT",
+	"sql": "SQL", "uri": "URI", "url": "URL", "uuid": "UUID", "xml": "XML", "html": "HTML",
+}
+
+// exported Go name of an identifier like "user_id", "userId" or "get /users/{id}"
+func goName(s string) string {
+	var b strings.Builder
+	for _, word := range splitWords(s) {
+		if initialism, found := initialisms[strings.ToLower(word)]; found {
+			b.WriteString(initialism)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteString(string(unicode.ToUpper(runes[0])) + string(runes[1:]))
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// words of an identifier, split at non alphanumeric characters and camel case humps
+func splitWords(s string) []string {
+	words := []string{}
+	current := []rune{}
+	var previous rune
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(current) > 0 {
+				words = append(words, string(current))
+			}
+			current = current[:0]
+			previous = 0
+			continue
+		}
+		if unicode.IsUpper(r) && unicode.IsLower(previous) && len(current) > 0 {
+			words = append(words, string(current))
+			current = current[:0]
+		}
+		current = append(current, r)
+		previous = r
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	return words
+}
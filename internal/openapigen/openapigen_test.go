@@ -0,0 +1,53 @@
+package openapigen
+
+import (
+	"go/format"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/loissascha/go-http-server/internal/openapidoc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	data, err := os.ReadFile("testdata/petstore.yaml")
+	assert.NoError(t, err)
+	doc, err := openapidoc.Parse(data)
+	assert.NoError(t, err)
+
+	code, err := Generate(doc, Config{Package: "petstore", Source: "petstore.yaml"})
+	assert.NoError(t, err)
+	formatted, err := format.Source(code)
+	assert.NoError(t, err)
+	assert.Equal(t, string(formatted), string(code))
+
+	out := string(code)
+	assert.Contains(t, out, "package petstore")
+	assert.Contains(t, out, "type Pet struct {\n\tNewPet\n")
+	assert.Contains(t, out, "Parent *Pet  `json:\"parent,omitempty\"`")
+	assert.Contains(t, out, "BornAt *time.Time `json:\"born_at,omitempty\"`")
+	assert.Contains(t, out, "PetStatusAvailable PetStatus = \"available\"")
+	assert.Contains(t, out, "Next  *string `json:\"next,omitempty\"`")
+	assert.Contains(t, out, "ListPets func(w http.ResponseWriter, r *http.Request)")
+	assert.Contains(t, out, "DeletePetsByPetID func(w http.ResponseWriter, r *http.Request)")
+	assert.Contains(t, out, "server.WithRequestBody[NewPet]()")
+	assert.Contains(t, out, "server.WithResponseType[Error](422)")
+	assert.Contains(t, out, "server.WithSecurity(\"oauth\", \"pets:write\")")
+	// combined schemes and anonymous access
+	assert.Contains(t, out, "server.WithSecurityRequirement(map[string][]string{\"apiKey\": {}, \"bearer\": {}}),\n\t\t\tserver.WithSecurityRequirement(map[string][]string{}),")
+	assert.Contains(t, out, "TokenUrl: \"https://auth.example.com/token\"")
+	assert.Contains(t, out, "{Url: \"https://petstore.example.com/v1\"}")
+
+	// listPets opts out of the global security requirement
+	listPets, _, _ := strings.Cut(out[strings.Index(out, "if h.ListPets != nil"):], "if h.CreatePet != nil")
+	assert.NotContains(t, listPets, "WithSecurity")
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "ShowPetByID", goName("showPetById"))
+	assert.Equal(t, "BornAt", goName("born_at"))
+	assert.Equal(t, "UserURL", goName("user-url"))
+	assert.Equal(t, "GetUsersID", goName("get /users/{id}"))
+	assert.Equal(t, "X2fa", goName("2fa"))
+}
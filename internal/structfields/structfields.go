@@ -0,0 +1,79 @@
+// Package structfields reads the JSON field information of structs. It is shared by the
+// TS export and OpenAPI schemas of the server and the encoders of the respond package.
+package structfields
+
+import (
+	"reflect"
+	"strings"
+)
+
+type FieldInfo struct {
+	GoName     string
+	TSName     string
+	JSONName   string
+	OmitEmpty  bool
+	Ignored    bool
+	Exported   bool
+	Anonymous  bool
+	Type       reflect.Type
+	TagRawJSON string
+}
+
+func ParseJSONTag(tag string) (name string, omitEmpty bool, ignored bool) {
+	if tag == "" {
+		return "", false, false
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func Parse(t reflect.Type) []FieldInfo {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var out []FieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		// skip unexported (PkgPath != "" means unexported, except embedded sometimes)
+		exported := sf.PkgPath == ""
+		if !exported && !sf.Anonymous {
+			continue
+		}
+
+		raw := sf.Tag.Get("json")
+		name, omitempty, ignored := ParseJSONTag(raw)
+
+		jsonName := name
+		if jsonName == "" && !ignored {
+			jsonName = sf.Name
+		}
+
+		fi := FieldInfo{
+			GoName:     sf.Name,
+			TSName:     jsonName,
+			JSONName:   jsonName,
+			OmitEmpty:  omitempty,
+			Ignored:    ignored,
+			Exported:   exported,
+			Anonymous:  sf.Anonymous,
+			Type:       sf.Type,
+			TagRawJSON: raw,
+		}
+		out = append(out, fi)
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -44,7 +45,7 @@ func main() {
 	s.GET("/test", homeHandler)
 
 	s.GET("/login", loginGet)
-	s.POST("/login", loginPost, server.WithExportType[loginInput](), server.WithExportType[loginResult]())
+	server.Handle(s, http.MethodPost, "/login", loginPost)
 
 	fmt.Println("server:", s)
 
@@ -54,13 +55,16 @@ func main() {
 	}
 }
 
-func loginPost(w http.ResponseWriter, r *http.Request) {
+func loginPost(ctx context.Context, in loginInput) (loginResult, error) {
+	if in.Username == "" {
+		return loginResult{}, server.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+	}
 	res := loginResult{
 		Method:  "POST",
 		Success: true,
 		Jwt:     "TEST",
 	}
-	respond.JSON(w, http.StatusOK, res)
+	return res, nil
 }
 
 func loginGet(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,109 @@
+package respond
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options of Content and File
+type ContentOptions struct {
+	// Display the content in the browser (Content-Disposition: inline) instead of downloading it
+	Inline bool
+	// Content type, detected from the name extension or sniffed from the content if empty
+	ContentType string
+	// Entity tag for If-None-Match and If-Range, quoted if necessary
+	ETag string
+}
+
+// Write content as download. Range (also multi-range) and conditional requests are handled by
+// http.ServeContent, the name is sent as RFC 6266 Content-Disposition filename.
+func Content(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker, opts ContentOptions) {
+	disposition := "attachment"
+	if opts.Inline {
+		disposition = "inline"
+	}
+	if name != "" {
+		disposition += "; " + dispositionFilename(name)
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	if opts.ContentType != "" {
+		w.Header().Set("Content-Type", opts.ContentType)
+	}
+	if opts.ETag != "" {
+		etag := opts.ETag
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		w.Header().Set("ETag", etag)
+	}
+	http.ServeContent(w, r, name, modtime, content)
+}
+
+// Write the file at path as download (see Content). Missing files result in a 404 problem.
+func File(w http.ResponseWriter, r *http.Request, path string, opts ContentOptions) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			Problem(w, r, http.StatusNotFound, ProblemDetails{})
+			return
+		}
+		Problem(w, r, http.StatusInternalServerError, ProblemDetails{})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		Problem(w, r, http.StatusNotFound, ProblemDetails{})
+		return
+	}
+	Content(w, r, filepath.Base(path), info.ModTime(), f, opts)
+}
+
+// filename parameter with ASCII fallback and UTF-8 filename* (RFC 6266, RFC 8187)
+func dispositionFilename(name string) string {
+	var fallback strings.Builder
+	plain := true
+	for _, c := range name {
+		switch {
+		case c < 0x20 || c > 0x7e:
+			fallback.WriteByte('_')
+			plain = false
+		case c == '"' || c == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(c)
+		default:
+			fallback.WriteRune(c)
+		}
+	}
+	result := `filename="` + fallback.String() + `"`
+	if plain {
+		return result
+	}
+
+	var encoded strings.Builder
+	for _, b := range []byte(name) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return result + "; filename*=UTF-8''" + encoded.String()
+}
+
+// attr-char of RFC 8187
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
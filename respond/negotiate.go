@@ -0,0 +1,305 @@
+package respond
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loissascha/go-http-server/internal/structfields"
+)
+
+// Returned by encoders that can't represent a payload (e.g. CSV for a map). Negotiate
+// tries the next acceptable encoder in that case.
+var ErrUnsupportedPayload = errors.New("payload not supported by encoder")
+
+// Encodes payloads for a media type (see RegisterEncoder)
+type Encoder interface {
+	Encode(w io.Writer, payload any) error
+}
+
+// Encoder function
+type EncoderFunc func(w io.Writer, payload any) error
+
+func (f EncoderFunc) Encode(w io.Writer, payload any) error {
+	return f(w, payload)
+}
+
+type registeredEncoder struct {
+	mediaType   string
+	contentType string
+	encoder     Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = []registeredEncoder{
+		{mediaType: "application/json", contentType: "application/json", encoder: EncoderFunc(encodeJSON)},
+		{mediaType: "application/xml", contentType: "application/xml; charset=utf-8", encoder: EncoderFunc(encodeXML)},
+		{mediaType: "text/xml", contentType: "text/xml; charset=utf-8", encoder: EncoderFunc(encodeXML)},
+		{mediaType: "text/csv", contentType: "text/csv; charset=utf-8", encoder: EncoderFunc(encodeCSV)},
+	}
+)
+
+// Register an encoder for a media type (e.g. "application/msgpack"). Replaces the encoder of
+// an already registered media type. On equal preference encoders registered first win,
+// JSON is the default.
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	for i, e := range encoders {
+		if e.mediaType == mediaType {
+			encoders[i].encoder = encoder
+			return
+		}
+	}
+	encoders = append(encoders, registeredEncoder{mediaType: mediaType, contentType: mediaType, encoder: encoder})
+}
+
+// Write the payload in the format the Accept header of the request prefers (JSON, XML, CSV
+// or a registered encoder). Responds with 406 if no acceptable format can encode the payload.
+func Negotiate(w http.ResponseWriter, r *http.Request, status int, payload any) {
+	w.Header().Add("Vary", "Accept")
+
+	for _, e := range acceptableEncoders(r.Header.Values("Accept")) {
+		var buf bytes.Buffer
+		err := e.encoder.Encode(&buf, payload)
+		if errors.Is(err, ErrUnsupportedPayload) {
+			continue
+		}
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", e.contentType)
+		w.WriteHeader(status)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	Problem(w, r, http.StatusNotAcceptable, ProblemDetails{
+		Detail: "none of the accepted media types can be produced",
+	})
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(values []string) []acceptRange {
+	ranges := []acceptRange{}
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			mediaType, params, _ := strings.Cut(part, ";")
+			mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+			if mediaType == "" {
+				continue
+			}
+			q := 1.0
+			for param := range strings.SplitSeq(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(key, "q") {
+					parsed, err := strconv.ParseFloat(val, 64)
+					if err == nil {
+						q = parsed
+					}
+				}
+			}
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	return ranges
+}
+
+// quality of the media type: the q value of the most specific matching range (-1 if none matches)
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q := -1.0
+	specificity := -1
+	for _, ar := range ranges {
+		s := -1
+		switch {
+		case ar.mediaType == mediaType:
+			s = 2
+		case ar.mediaType == typ+"/*":
+			s = 1
+		case ar.mediaType == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			specificity = s
+			q = ar.q
+		}
+	}
+	return q
+}
+
+// the encoders acceptable for the Accept header, best first
+func acceptableEncoders(accept []string) []registeredEncoder {
+	encodersMu.RLock()
+	all := append([]registeredEncoder{}, encoders...)
+	encodersMu.RUnlock()
+
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return all
+	}
+
+	type candidate struct {
+		encoder registeredEncoder
+		q       float64
+	}
+	candidates := []candidate{}
+	for _, e := range all {
+		q := acceptQuality(ranges, e.mediaType)
+		if q <= 0 {
+			continue
+		}
+		// stable insert by quality, registration order decides on equal quality
+		i := len(candidates)
+		for i > 0 && candidates[i-1].q < q {
+			i--
+		}
+		candidates = append(candidates[:i], append([]candidate{{encoder: e, q: q}}, candidates[i:]...)...)
+	}
+
+	result := []registeredEncoder{}
+	for _, c := range candidates {
+		result = append(result, c.encoder)
+	}
+	return result
+}
+
+func encodeJSON(w io.Writer, payload any) error {
+	return json.NewEncoder(w).Encode(payload)
+}
+
+// XML, slices and arrays are wrapped in an <items> root element
+func encodeXML(w io.Writer, payload any) error {
+	io.WriteString(w, xml.Header)
+	err := encodeXMLDocument(xml.NewEncoder(w), payload)
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return fmt.Errorf("%w: %v", ErrUnsupportedPayload, err)
+	}
+	return err
+}
+
+func encodeXMLDocument(enc *xml.Encoder, payload any) error {
+	v := reflect.ValueOf(payload)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return enc.Encode(payload)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: "items"}}
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		err = enc.Encode(v.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	err = enc.EncodeToken(start.End())
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// CSV for structs and slices of structs, the header row are the JSON names of the fields
+func encodeCSV(w io.Writer, payload any) error {
+	v := reflect.ValueOf(payload)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ErrUnsupportedPayload
+		}
+		v = v.Elem()
+	}
+
+	rows := []reflect.Value{}
+	var t reflect.Type
+	switch v.Kind() {
+	case reflect.Struct:
+		t = v.Type()
+		rows = append(rows, v)
+	case reflect.Slice, reflect.Array:
+		t = v.Type().Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		for i := 0; i < v.Len(); i++ {
+			rows = append(rows, v.Index(i))
+		}
+	default:
+		return ErrUnsupportedPayload
+	}
+	if t.Kind() != reflect.Struct {
+		return ErrUnsupportedPayload
+	}
+
+	fields := []structfields.FieldInfo{}
+	header := []string{}
+	for _, info := range structfields.Parse(t) {
+		if info.Ignored || !info.Exported {
+			continue
+		}
+		fields = append(fields, info)
+		header = append(header, info.JSONName)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range rows {
+		for row.Kind() == reflect.Pointer {
+			row = row.Elem()
+		}
+		record := make([]string, len(fields))
+		if row.IsValid() {
+			for i, info := range fields {
+				record[i] = csvValue(row.FieldByName(info.GoName))
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,52 @@
+package respond
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+)
+
+// RFC 9457 problem details. Extensions are written as additional top level members.
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	m := map[string]any{}
+	maps.Copy(m, p.Extensions)
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// Write a application/problem+json response. Type defaults to "about:blank", Title to the
+// status text and Instance to the request path.
+func Problem(w http.ResponseWriter, r *http.Request, status int, problem ProblemDetails) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(status)
+	}
+	if problem.Instance == "" && r != nil {
+		problem.Instance = r.URL.Path
+	}
+	problem.Status = status
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,140 @@
+package respond
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type negotiateUser struct {
+	ID     int    `json:"id" xml:"id"`
+	Name   string `json:"name" xml:"name"`
+	Secret string `json:"-" xml:"-"`
+}
+
+func negotiate(accept string, payload any) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	Negotiate(w, r, http.StatusOK, payload)
+	return w
+}
+
+func TestNegotiate(t *testing.T) {
+	users := []negotiateUser{{ID: 1, Name: "Anna", Secret: "x"}, {ID: 2, Name: "Ben, Jr."}}
+
+	w := negotiate("", users)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `[{"id":1,"name":"Anna"},{"id":2,"name":"Ben, Jr."}]`, strings.TrimSpace(w.Body.String()))
+
+	w = negotiate("text/html, text/csv;q=0.9, application/json;q=0.5", users)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name\n1,Anna\n2,\"Ben, Jr.\"\n", w.Body.String())
+
+	w = negotiate("application/*;q=0.8, application/json;q=0.1", negotiateUser{ID: 1, Name: "Anna"})
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<negotiateUser><id>1</id><name>Anna</name></negotiateUser>")
+
+	// slices get a single root element
+	w = negotiate("application/xml", users)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+"<items><negotiateUser><id>1</id><name>Anna</name></negotiateUser><negotiateUser><id>2</id><name>Ben, Jr.</name></negotiateUser></items>", w.Body.String())
+	var decoded struct {
+		Users []negotiateUser `xml:"negotiateUser"`
+	}
+	assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Len(t, decoded.Users, 2)
+
+	// CSV can't encode maps, JSON is the next acceptable format
+	w = negotiate("text/csv, */*;q=0.1", map[string]string{"status": "ok"})
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	w = negotiate("text/html, application/json;q=0", users)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("text/plain", EncoderFunc(func(w io.Writer, payload any) error {
+		_, err := io.WriteString(w, "plain")
+		return err
+	}))
+
+	w := negotiate("text/plain", negotiateUser{})
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "plain", w.Body.String())
+}
+
+func serveContent(header map[string]string, opts ContentOptions) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/export", nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	Content(w, r, "Übersicht 2024.csv", modtime, strings.NewReader("id,name\n1,Anna\n"), opts)
+	return w
+}
+
+func TestContent(t *testing.T) {
+	w := serveContent(nil, ContentOptions{ETag: "v1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="_bersicht 2024.csv"; filename*=UTF-8''%C3%9Cbersicht%202024.csv`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "id,name\n1,Anna\n", w.Body.String())
+
+	w = serveContent(map[string]string{"If-None-Match": `"v1"`}, ContentOptions{ETag: "v1"})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serveContent(map[string]string{"If-Modified-Since": "Tue, 02 Jan 2024 03:04:05 GMT"}, ContentOptions{})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serveContent(map[string]string{"Range": "bytes=0-1"}, ContentOptions{Inline: true, ContentType: "text/plain"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "id", w.Body.String())
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Disposition"), "inline; "))
+
+	w = serveContent(map[string]string{"Range": "bytes=0-1,8-8"}, ContentOptions{})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges"))
+}
+
+func TestDispositionFilename(t *testing.T) {
+	assert.Equal(t, `filename="report.pdf"`, dispositionFilename("report.pdf"))
+	assert.Equal(t, `filename="a \"quoted\" name.txt"`, dispositionFilename(`a "quoted" name.txt`))
+	assert.Equal(t, `filename="____.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC%E8%AA%9E%E3%83%86.txt`, dispositionFilename("日本語テ.txt"))
+}
+
+func TestFileNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	File(w, httptest.NewRequest(http.MethodGet, "/export", nil), "does-not-exist.csv", ContentOptions{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSSEHeartbeatStopsOnClose(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream, err := SSE(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.NoError(t, err)
+	stream.Heartbeat(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	stream.Close()
+	stream.Close()
+
+	body := w.Body.String()
+	assert.Contains(t, body, ": heartbeat\n\n")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, body, w.Body.String())
+	assert.Error(t, stream.Send(SSEEvent{Data: "late"}))
+}
@@ -0,0 +1,187 @@
+package respond
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server-Sent Event. Data is written as is for strings and []byte, everything else as JSON.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration // reconnection time hint for the client
+}
+
+// Server-Sent Events stream (see SSE)
+type SSEStream struct {
+	mu           sync.Mutex
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	ctx          context.Context
+	lastEventID  string
+	writeTimeout time.Duration
+	closed       bool
+	stop         chan struct{}
+	heartbeats   sync.WaitGroup
+}
+
+var errSSEClosed = errors.New("sse: stream closed")
+
+const defaultSSEWriteTimeout = 10 * time.Second
+
+// Start a Server-Sent Events stream. The write deadline of the server (WriteTimeout) is
+// replaced by a deadline per write (see SetWriteTimeout).
+func SSE(w http.ResponseWriter, r *http.Request) (*SSEStream, error) {
+	stream := &SSEStream{
+		w:            w,
+		rc:           http.NewResponseController(w),
+		ctx:          r.Context(),
+		lastEventID:  r.Header.Get("Last-Event-ID"),
+		writeTimeout: defaultSSEWriteTimeout,
+		stop:         make(chan struct{}),
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Del("Content-Length")
+
+	err := stream.rc.SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+	w.WriteHeader(http.StatusOK)
+	err = stream.rc.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("sse: streaming not supported: %w", err)
+	}
+	return stream, nil
+}
+
+// ID of the last event the client received before reconnecting (Last-Event-ID header)
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Context of the stream, done when the client disconnects
+func (s *SSEStream) Context() context.Context {
+	return s.ctx
+}
+
+// Maximum duration of a single write (0 disables the deadline)
+func (s *SSEStream) SetWriteTimeout(d time.Duration) {
+	s.mu.Lock()
+	s.writeTimeout = d
+	s.mu.Unlock()
+}
+
+// Send an event
+func (s *SSEStream) Send(event SSEEvent) error {
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + singleLine(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + singleLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	if event.Data != nil {
+		data, err := sseData(event.Data)
+		if err != nil {
+			return err
+		}
+		for line := range strings.SplitSeq(data, "\n") {
+			b.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Send a comment (ignored by clients, keeps the connection alive)
+func (s *SSEStream) Comment(text string) error {
+	return s.write(": " + singleLine(text) + "\n\n")
+}
+
+// Send a comment every interval until the stream context is done or the stream is closed
+func (s *SSEStream) Heartbeat(interval time.Duration) {
+	s.heartbeats.Add(1)
+	go func() {
+		defer s.heartbeats.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if s.Comment("heartbeat") != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Stop the stream: heartbeats are stopped and waited for, later writes fail. Has to be
+// called before the handler returns, the ResponseWriter can't be used afterwards.
+func (s *SSEStream) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+	s.heartbeats.Wait()
+}
+
+func (s *SSEStream) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSSEClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if s.writeTimeout > 0 {
+		s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	}
+	_, err := s.w.Write([]byte(data))
+	if err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func sseData(data any) (string, error) {
+	switch d := data.(type) {
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Bind the request into v (pointer to a struct). The JSON body is decoded first (see DecodeJSON), afterwards
+// fields tagged with `path:"name"`, `query:"name"` or `header:"Name"` are set from
+// r.PathValue, the query string and the request headers (never from the body).
+// Fields of embedded structs are bound as well.
+// Form requests set the fields tagged with `form:"name"` instead of decoding JSON. Multipart
+// files are parsed with ParseUpload and bound to UploadedFile, *UploadedFile or []UploadedFile fields.
+func Bind(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: expected pointer to struct, got %T", v)
+	}
+
+	var form *Upload
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case contentType == "multipart/form-data":
+		upload, err := ParseUpload(r)
+		if err != nil {
+			return err
+		}
+		form = upload
+	case contentType == "application/x-www-form-urlencoded":
+		err := r.ParseForm()
+		if err != nil {
+			return uploadError(err)
+		}
+		form = &Upload{Values: r.PostForm}
+	case r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0:
+		// path, query and header fields are only set from their source, not from the body
+		saved := map[string]reflect.Value{}
+		walkSourceFields(rv.Elem(), "", func(key string, field reflect.Value) {
+			value := reflect.New(field.Type()).Elem()
+			value.Set(field)
+			saved[key] = value
+		})
+		err := DecodeJSON(r, v)
+		walkSourceFields(rv.Elem(), "", func(key string, field reflect.Value) {
+			if value, found := saved[key]; found {
+				field.Set(value)
+			} else {
+				field.SetZero()
+			}
+		})
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	return bindStructValues(r, rv.Elem(), form)
+}
+
+// calls fn for the path, query and header fields of v, including the ones of embedded structs
+func walkSourceFields(v reflect.Value, key string, fn func(key string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		field := v.Field(i)
+		fieldKey := fmt.Sprintf("%s.%d", key, i)
+		if embedded, ok := embeddedStruct(sf, field); ok {
+			walkSourceFields(embedded, fieldKey, fn)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		for _, tag := range []string{"path", "query", "header"} {
+			if _, ok := sf.Tag.Lookup(tag); ok {
+				fn(fieldKey, field)
+				break
+			}
+		}
+	}
+}
+
+// the struct value of an embedded struct (or non-nil pointer to a struct) field
+func embeddedStruct(sf reflect.StructField, field reflect.Value) (reflect.Value, bool) {
+	if !sf.Anonymous {
+		return reflect.Value{}, false
+	}
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return reflect.Value{}, false
+		}
+		field = field.Elem()
+	}
+	return field, field.Kind() == reflect.Struct
+}
+
+func bindStructValues(r *http.Request, v reflect.Value, form *Upload) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous {
+			err := bindEmbeddedValues(r, sf, v.Field(i), form)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name, ok := sf.Tag.Lookup("path"); ok {
+			if value := r.PathValue(name); value != "" {
+				if err := setFieldValue(v.Field(i), []string{value}); err != nil {
+					return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid path parameter %q: %v", name, err))
+				}
+			}
+		}
+		if name, ok := sf.Tag.Lookup("query"); ok {
+			if values, found := r.URL.Query()[name]; found {
+				if err := setFieldValue(v.Field(i), values); err != nil {
+					return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid query parameter %q: %v", name, err))
+				}
+			}
+		}
+		if name, ok := sf.Tag.Lookup("header"); ok {
+			if values := r.Header.Values(name); len(values) > 0 {
+				if err := setFieldValue(v.Field(i), values); err != nil {
+					return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid header %q: %v", name, err))
+				}
+			}
+		}
+		if name, ok := sf.Tag.Lookup("form"); ok && form != nil && !bindFormFiles(v.Field(i), form, name) {
+			if values, found := form.Values[name]; found {
+				if err := setFieldValue(v.Field(i), values); err != nil {
+					return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid form field %q: %v", name, err))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// binds the fields of embedded structs, nil pointers are only allocated if a field is set
+func bindEmbeddedValues(r *http.Request, sf reflect.StructField, field reflect.Value, form *Upload) error {
+	if embedded, ok := embeddedStruct(sf, field); ok {
+		return bindStructValues(r, embedded, form)
+	}
+	if field.Kind() != reflect.Pointer || field.Type().Elem().Kind() != reflect.Struct || !field.CanSet() {
+		return nil
+	}
+	embedded := reflect.New(field.Type().Elem())
+	err := bindStructValues(r, embedded.Elem(), form)
+	if err != nil {
+		return err
+	}
+	if !embedded.Elem().IsZero() {
+		field.Set(embedded)
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+var uploadedFileType = reflect.TypeFor[UploadedFile]()
+
+// sets UploadedFile fields, returns false for other field types
+func bindFormFiles(field reflect.Value, form *Upload, name string) bool {
+	switch field.Type() {
+	case uploadedFileType:
+		if file, found := form.File(name); found {
+			field.Set(reflect.ValueOf(file))
+		}
+	case reflect.PointerTo(uploadedFileType):
+		if file, found := form.File(name); found {
+			field.Set(reflect.ValueOf(&file))
+		}
+	case reflect.SliceOf(uploadedFileType):
+		files := []UploadedFile{}
+		for _, file := range form.Files {
+			if file.Field == name {
+				files = append(files, file)
+			}
+		}
+		field.Set(reflect.ValueOf(files))
+	default:
+		return false
+	}
+	return true
+}
+
+func setFieldValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFieldValue(field.Elem(), values)
+	}
+
+	if reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	}
+
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFieldValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	value := values[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
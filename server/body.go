@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const DEFAULT_MAX_BODY_BYTES = 10 << 20
+
+// Limit the request body of this route (overrides the server default, n <= 0 disables the limit)
+func WithMaxBodyBytes(n int64) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.MaxBodyBytes = &n
+	}
+}
+
+// Decode JSON bodies of this route strictly (see DecodeStrictJSON)
+func WithStrictJSON() RouteOption {
+	return func(ri *RouteInfo) {
+		ri.StrictJSON = true
+	}
+}
+
+type strictJSONKey struct{}
+
+// body limit of the route: the route option, the upload/tus limits or the server default
+func (s *Server) routeMaxBodyBytes(info RouteInfo) int64 {
+	if info.MaxBodyBytes != nil {
+		return *info.MaxBodyBytes
+	}
+	if info.Upload != nil || info.Tus != nil {
+		// limited by UploadConfig.MaxBytes and TusConfig.MaxSize
+		return 0
+	}
+	return s.MaxBodyBytes
+}
+
+// rejects bodies above limit with 413 and marks strict JSON routes
+func (s *Server) bodyMiddleware(limit int64, strictJSON bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit > 0 {
+				if r.ContentLength > limit {
+					s.writeError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", limit))
+					return
+				}
+				if r.Body != nil {
+					r.Body = http.MaxBytesReader(w, r.Body, limit)
+				}
+			}
+			if strictJSON {
+				r = r.WithContext(context.WithValue(r.Context(), strictJSONKey{}, true))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Decode the JSON request body into v. Routes with WithStrictJSON decode strictly (see
+// DecodeStrictJSON). Returns a 400 HTTPError for invalid JSON and a 413 for too large bodies.
+func DecodeJSON(r *http.Request, v any) error {
+	var err error
+	if strict, _ := r.Context().Value(strictJSONKey{}).(bool); strict {
+		err = DecodeStrictJSON(r.Body, v)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(v)
+	}
+	if err == nil {
+		return nil
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large", Err: err}
+	}
+	if errors.Is(err, io.EOF) {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "empty JSON body", Err: err}
+	}
+	return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+}
+
+// Decode a single JSON value into v, rejecting unknown fields, duplicate object keys and
+// data after the value
+func DecodeStrictJSON(reader io.Reader, v any) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return io.EOF
+	}
+
+	err = checkDuplicateJSONKeys(json.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(v)
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// walks a single JSON value and fails on objects containing a key twice
+func checkDuplicateJSONKeys(dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		keys := map[string]bool{}
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			if keys[key] {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			keys[key] = true
+			err = checkDuplicateJSONKeys(dec)
+			if err != nil {
+				return err
+			}
+		}
+	case '[':
+		for dec.More() {
+			err := checkDuplicateJSONKeys(dec)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// closing delimiter
+	_, err = dec.Token()
+	return err
+}
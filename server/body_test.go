@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type strictInput struct {
+	Name  string `json:"name"`
+	Items []struct {
+		ID int `json:"id"`
+	} `json:"items"`
+}
+
+func TestDecodeStrictJSON(t *testing.T) {
+	var in strictInput
+	assert.NoError(t, DecodeStrictJSON(strings.NewReader(`{"name":"a","items":[{"id":1},{"id":2}]} `), &in))
+	assert.Equal(t, "a", in.Name)
+	assert.Len(t, in.Items, 2)
+
+	assert.ErrorContains(t, DecodeStrictJSON(strings.NewReader(`{"name":"a","admin":true}`), &in), "unknown field")
+	assert.ErrorContains(t, DecodeStrictJSON(strings.NewReader(`{"name":"a","name":"b"}`), &in), "duplicate key")
+	assert.ErrorContains(t, DecodeStrictJSON(strings.NewReader(`{"items":[{"id":1,"id":2}]}`), &in), "duplicate key")
+	assert.ErrorContains(t, DecodeStrictJSON(strings.NewReader(`{"name":"a"}{"name":"b"}`), &in), "after JSON value")
+	assert.ErrorIs(t, DecodeStrictJSON(strings.NewReader(" "), &in), io.EOF)
+}
+
+func TestBodyLimitAndStrictJSON(t *testing.T) {
+	s, err := NewServer(SetMaxBodyBytes(32), EnableProblemDetails())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(32), s.MaxBodyBytes)
+
+	echo := func(ctx context.Context, in strictInput) (strictInput, error) {
+		return in, nil
+	}
+	Handle(s, http.MethodPost, "/default", echo)
+	Handle(s, http.MethodPost, "/large", echo, WithMaxBodyBytes(1024))
+	Handle(s, http.MethodPost, "/strict", echo, WithStrictJSON())
+	s.POST("/raw", func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(url string, body io.Reader) (int, string) {
+		resp, err := http.Post(ts.URL+url, "application/json", body)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, resp.Header.Get("Content-Type") + " " + string(b)
+	}
+
+	large := `{"name":"` + strings.Repeat("x", 64) + `"}`
+	status, body := post("/default", strings.NewReader(large))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.True(t, strings.HasPrefix(body, "application/problem+json"))
+
+	status, _ = post("/large", strings.NewReader(large))
+	assert.Equal(t, http.StatusOK, status)
+
+	// without Content-Length the limit applies while reading
+	status, _ = post("/raw", io.MultiReader(strings.NewReader(large)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	status, _ = post("/default", strings.NewReader(`{"name":"a","x":1}`))
+	assert.Equal(t, http.StatusOK, status)
+	status, body = post("/strict", strings.NewReader(`{"name":"a","x":1}`))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "unknown field")
+}
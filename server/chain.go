@@ -1,12 +1,60 @@
 package server
 
-import "net/http"
+import (
+	"net/http"
+	"slices"
+)
 
-func chainMiddleware(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
-	h = recoverMiddleware(h)
+func (s *Server) chainMiddleware(h http.Handler, cors *corsPolicy, middlewares ...func(http.Handler) http.Handler) http.Handler {
+	h = s.recoverMiddleware(h)
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		h = middlewares[i](h)
 	}
-	h = corsMiddleware(h)
+	h = cors.middleware(h, s.writeError)
+	return h
+}
+
+// Add middlewares for every request of the server (routes, Handle, mounts, redirects, 404s and 405s).
+//
+// Order: UseBefore -> CORS -> Use -> route middlewares -> UseAfter -> recover -> handler
+func (s *Server) Use(middlewares ...func(http.Handler) http.Handler) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// Add middlewares that run before the CORS middleware (e.g. request ids)
+func (s *Server) UseBefore(middlewares ...func(http.Handler) http.Handler) {
+	s.beforeMiddlewares = append(s.beforeMiddlewares, middlewares...)
+}
+
+// Add middlewares that run after the route middlewares, right before the handler
+func (s *Server) UseAfter(middlewares ...func(http.Handler) http.Handler) {
+	s.afterMiddlewares = append(s.afterMiddlewares, middlewares...)
+}
+
+// chainMiddleware including the global middlewares and the CORS policy of the server
+func (s *Server) chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
+	return s.chainWithCORS(h, nil, middlewares...)
+}
+
+// chainMiddleware including the global middlewares of the server. Uses the CORS policy of
+// the server if cors is nil.
+func (s *Server) chainWithCORS(h http.Handler, cors *corsPolicy, middlewares ...func(http.Handler) http.Handler) http.Handler {
+	if cors == nil {
+		cors = s.corsPolicy
+	}
+	h = s.chainMiddleware(h, cors, slices.Concat(s.middlewares, middlewares, s.afterMiddlewares)...)
+	for i := len(s.beforeMiddlewares) - 1; i >= 0; i-- {
+		h = s.beforeMiddlewares[i](h)
+	}
+	return h
+}
+
+// the global middlewares of the server without CORS and recover (for mounted sub servers
+// which bring their own)
+func (s *Server) chainWithoutBuiltins(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
+	all := slices.Concat(s.beforeMiddlewares, s.middlewares, middlewares, s.afterMiddlewares)
+	for i := len(all) - 1; i >= 0; i-- {
+		h = all[i](h)
+	}
 	return h
 }
@@ -1,82 +1,201 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/loissascha/go-logger/logger"
 )
 
-func corsMiddleware(next http.Handler) http.Handler {
+// CORS policy of a server (WithCORS) or a single route (WithRouteCORS)
+type CORSConfig struct {
+	// Allowed origins. "*" allows every origin, patterns like "https://*.example.com" allow all subdomains.
+	AllowedOrigins []string
+	// Reflect http://localhost:* and http://127.0.0.1:* origins (development convenience)
+	AllowLocalhost bool
+	// Methods and request headers allowed in preflights, empty uses the defaults
+	// (defaultCORSMethods, defaultCORSHeaders)
+	AllowedMethods      []string
+	AllowedHeaders      []string
+	ExposedHeaders      []string
+	MaxAge              time.Duration
+	AllowCredentials    bool
+	AllowPrivateNetwork bool
+}
+
+var (
+	defaultCORSMethods = []string{"POST", "GET", "OPTIONS", "PUT", "DELETE", "PATCH", "HEAD"}
+	defaultCORSHeaders = []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Requested-With", "Origin"}
+)
+
+const defaultDevelopmentOrigins = "http://localhost:4321,http://localhost:4322,http://127.0.0.1:4321,http://127.0.0.1:4322,http://localhost:5173,http://127.0.0.1:5173,http://localhost:3000,http://127.0.0.1:3000"
+
+// CORS config built from the ALLOWED_ORIGINS and APP_ENV environment variables (default of every server)
+func CORSConfigFromEnv() (CORSConfig, error) {
+	production := os.Getenv("APP_ENV") == "production"
 	rawAllowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-	allowAllOrigins := false
-	if rawAllowedOrigins == "*" {
-		allowAllOrigins = true
-	}
-	if rawAllowedOrigins == "" && os.Getenv("APP_ENV") == "production" {
+	if rawAllowedOrigins == "" && production {
 		logger.Warning(nil, "Allowed origins is not set! Please make sure to configure your .env file!")
 	} else if rawAllowedOrigins == "" {
 		logger.Warning(nil, "Allowed origins is not set! Allowing development hosts!")
-		rawAllowedOrigins = "http://localhost:4321,http://localhost:4322,http://127.0.0.1:4321,http://127.0.0.1:4322,http://localhost:5173,http://127.0.0.1:5173,http://localhost:3000,http://127.0.0.1:3000"
+		rawAllowedOrigins = defaultDevelopmentOrigins
 	}
-	allowedOriginsList := strings.Split(rawAllowedOrigins, ",")
-	allowedOriginsMap := make(map[string]bool)
-	for _, origin := range allowedOriginsList {
+
+	allowedOrigins := []string{}
+	for origin := range strings.SplitSeq(rawAllowedOrigins, ",") {
 		trimmedOrigin := strings.TrimSpace(origin)
 		if trimmedOrigin != "" {
-			allowedOriginsMap[trimmedOrigin] = true
+			allowedOrigins = append(allowedOrigins, trimmedOrigin)
 		}
 	}
-	if len(allowedOriginsMap) == 0 && os.Getenv("APP_ENV") == "production" {
-		logger.Fatal(nil, "FATAL: No valid allowed origins configured for CORS in production.")
-		panic("CORS not configured!")
+	if len(allowedOrigins) == 0 && production {
+		return CORSConfig{}, errors.New("CORS not configured: no valid allowed origins configured for CORS in production")
 	}
 
+	return CORSConfig{
+		AllowedOrigins:   allowedOrigins,
+		AllowLocalhost:   !production,
+		AllowedMethods:   slices.Clone(defaultCORSMethods),
+		AllowedHeaders:   slices.Clone(defaultCORSHeaders),
+		AllowCredentials: true,
+	}, nil
+}
+
+type originPattern struct {
+	prefix string
+	suffix string
+}
+
+type corsPolicy struct {
+	config          CORSConfig
+	allowAllOrigins bool
+	allowedOrigins  map[string]bool
+	patterns        []originPattern
+	allowMethods    string
+	allowHeaders    string
+	exposeHeaders   string
+	maxAge          string
+}
+
+func newCORSPolicy(config CORSConfig) *corsPolicy {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = defaultCORSMethods
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = defaultCORSHeaders
+	}
+	p := &corsPolicy{
+		config:         config,
+		allowedOrigins: map[string]bool{},
+		allowMethods:   strings.Join(config.AllowedMethods, ", "),
+		allowHeaders:   strings.Join(config.AllowedHeaders, ", "),
+		exposeHeaders:  strings.Join(config.ExposedHeaders, ", "),
+	}
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			p.allowAllOrigins = true
+			continue
+		}
+		if prefix, suffix, found := strings.Cut(origin, "*"); found {
+			p.patterns = append(p.patterns, originPattern{prefix: prefix, suffix: suffix})
+			continue
+		}
+		p.allowedOrigins[origin] = true
+	}
+	return p
+}
+
+func (p *corsPolicy) originAllowed(origin string) bool {
+	if p.allowAllOrigins || p.allowedOrigins[origin] {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if len(origin) <= len(pattern.prefix)+len(pattern.suffix) ||
+			!strings.HasPrefix(origin, pattern.prefix) || !strings.HasSuffix(origin, pattern.suffix) {
+			continue
+		}
+		// the wildcard only stands for subdomain labels, not for a scheme, port or path
+		sub := origin[len(pattern.prefix) : len(origin)-len(pattern.suffix)]
+		if !strings.ContainsAny(sub, ":/") {
+			return true
+		}
+	}
+	if p.config.AllowLocalhost && (strings.HasPrefix(origin, "http://localhost:") || strings.HasPrefix(origin, "http://127.0.0.1:")) {
+		return true
+	}
+	return false
+}
+
+func (p *corsPolicy) middleware(next http.Handler, writeError func(w http.ResponseWriter, r *http.Request, status int, message string)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestOrigin := r.Header.Get("Origin")
 		originAllowed := false
-		// logger.Info(nil, "Request Origin: {origin}", requestOrigin)
 
 		if requestOrigin != "" {
-			if _, ok := allowedOriginsMap[requestOrigin]; ok {
+			w.Header().Add("Vary", "Origin")
+			if p.originAllowed(requestOrigin) {
 				w.Header().Set("Access-Control-Allow-Origin", requestOrigin)
 				originAllowed = true
-			} else {
-				if os.Getenv("APP_ENV") != "production" && (strings.HasPrefix(requestOrigin, "http://localhost:") || strings.HasPrefix(requestOrigin, "http://127.0.0.1:")) {
-					w.Header().Set("Access-Control-Allow-Origin", requestOrigin)
-					originAllowed = true
-					// logger.Info(nil, "CORS: Development: Allowed reflected origin {origin}", requestOrigin)
-				}
 			}
 		} else {
 			// No Origin header usually means same-origin or a server-to-server request.
 			originAllowed = true
 		}
 
-		if allowAllOrigins {
-			originAllowed = true
-			if requestOrigin != "" {
-				w.Header().Set("Access-Control-Allow-Origin", requestOrigin)
+		preflight := isPreflightRequest(r)
+		if originAllowed || preflight {
+			if p.config.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
+			w.Header().Set("Access-Control-Allow-Methods", p.allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", p.allowHeaders)
 		}
 
-		if originAllowed || r.Method == "OPTIONS" {
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH, HEAD")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Requested-With, Origin")
-		}
-
-		if r.Method == "OPTIONS" {
+		if preflight {
 			if originAllowed { // Only respond positively to OPTIONS if the origin will be allowed
+				if p.maxAge != "" {
+					w.Header().Set("Access-Control-Max-Age", p.maxAge)
+				}
+				if p.config.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+					w.Header().Set("Access-Control-Allow-Private-Network", "true")
+				}
 				w.WriteHeader(http.StatusNoContent)
 			} else {
 				// Origin not allowed, so preflight should effectively fail.
-				http.Error(w, "CORS: Origin not allowed", http.StatusForbidden)
+				writeError(w, r, http.StatusForbidden, "CORS: Origin not allowed")
 			}
 			return
 		}
 
+		if originAllowed && p.exposeHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", p.exposeHeaders)
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+// a preflight is an OPTIONS request announcing the method of the actual request
+func isPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// the CORS policy of the route handling the announced method of a preflight
+func (m routeMethodHandlers) preflightPolicy(r *http.Request) *corsPolicy {
+	method := Method(r.Header.Get("Access-Control-Request-Method"))
+	if ch, found := m[method]; found {
+		return ch.cors
+	}
+	if ch, found := m[METHOD_GET]; found && method == METHOD_HEAD {
+		return ch.cors
+	}
+	return nil
+}
@@ -1,44 +1,13 @@
 package server
 
-import (
-	"fmt"
-	"net/http"
-)
+import "net/http"
 
 // DELETE route (ignores translation rules)
 func (s *Server) DELETEI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
-	routeInfo := getRouteInfos(opts...)
-
-	s.addPath(route, ServerPath{
-		Route:   route,
-		Method:  METHOD_DELETE,
-		Info:    routeInfo,
-		Handler: h,
-	})
+	s.RouteI(http.MethodDelete, route, h, opts...)
 }
 
 // DELETE route
 func (s *Server) DELETE(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
-	if !s.TranslationsEnabled {
-		s.DELETEI(route, h, opts...)
-		return
-	}
-
-	routeInfo := getRouteInfos(opts...)
-	s.addPath(route, ServerPath{
-		Route:   route,
-		Method:  METHOD_DELETE,
-		Info:    routeInfo,
-		Handler: s.redirectToTranslatedUrl,
-	})
-
-	for short := range s.Languages {
-		r := fmt.Sprintf("/%s%s", short, route)
-		s.addPath(r, ServerPath{
-			Route:   r,
-			Method:  METHOD_DELETE,
-			Info:    routeInfo,
-			Handler: h,
-		})
-	}
+	s.Route(http.MethodDelete, route, h, opts...)
 }
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Where a route was registered
+type RouteRegistration struct {
+	Method   Method
+	Route    string
+	Location string // file:line of the registering call
+}
+
+func (r RouteRegistration) String() string {
+	name := r.Route
+	if r.Method != "" {
+		name = fmt.Sprintf("%s %s", r.Method, r.Route)
+	}
+	if r.Location == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, r.Location)
+}
+
+// Returned by Validate/Serve if the same method and route (or mount prefix) was registered twice
+type RouteConflictError struct {
+	First  RouteRegistration
+	Second RouteRegistration
+}
+
+func (e *RouteConflictError) Error() string {
+	return fmt.Sprintf("route conflict: %s is already registered by %s", e.Second, e.First)
+}
+
+// Returned by Validate/Serve if a route was registered with an invalid method
+type InvalidMethodError struct {
+	Registration RouteRegistration
+}
+
+func (e *InvalidMethodError) Error() string {
+	return fmt.Sprintf("invalid method %q for route %s", e.Registration.Method, e.Registration)
+}
+
+// Returned by Validate/Serve if a route requires a security scheme that wasn't registered
+// with WithSecurityScheme
+type UnknownSecuritySchemeError struct {
+	Scheme       string
+	Registration RouteRegistration
+}
+
+func (e *UnknownSecuritySchemeError) Error() string {
+	return fmt.Sprintf("unknown security scheme %q for route %s", e.Scheme, e.Registration)
+}
+
+// Returned by Validate/Serve if a typed handler (see Handle) has an input type that isn't
+// a struct or a pointer to a struct
+type InvalidInputTypeError struct {
+	Type         reflect.Type
+	Registration RouteRegistration
+}
+
+func (e *InvalidInputTypeError) Error() string {
+	return fmt.Sprintf("invalid input type %s for route %s: expected struct or pointer to struct", e.Type, e.Registration)
+}
+
+// Returned by Validate/Serve if the input type of a typed handler has validate tags with
+// unknown rules or invalid parameters
+type InvalidValidationTagError struct {
+	Err          error
+	Registration RouteRegistration
+}
+
+func (e *InvalidValidationTagError) Error() string {
+	return fmt.Sprintf("%s of route %s", e.Err, e.Registration)
+}
+
+func (e *InvalidValidationTagError) Unwrap() error {
+	return e.Err
+}
+
+// Check the registered routes. Returns all registration errors (e.g. *RouteConflictError)
+// of this server and its mounted sub servers. Serve and ServeTLS call this before starting.
+func (s *Server) Validate() error {
+	errs := append([]error{}, s.registrationErrors...)
+	for _, m := range s.mounts {
+		if child, ok := m.handler.(*Server); ok {
+			if err := child.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("mount %s: %w", m.prefix, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// file:line of the first caller outside of this package (tests of this package count as outside)
+func registrationLocation() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/loissascha/go-http-server/server.") || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
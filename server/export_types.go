@@ -5,26 +5,18 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+
+	"github.com/loissascha/go-http-server/internal/structfields"
 )
 
-type StructFieldInfo struct {
-	GoName     string
-	TSName     string
-	JSONName   string
-	OmitEmpty  bool
-	Ignored    bool
-	Exported   bool
-	Anonymous  bool
-	Type       reflect.Type
-	TagRawJSON string
-}
+type StructFieldInfo = structfields.FieldInfo
 
 func (s *Server) exportInterfacesToTS() error {
 	allInterfaces := ""
 
 	exportedTypes := []reflect.Type{}
 
-	for _, paths := range s.Paths {
+	for _, paths := range s.allPaths() {
 		for _, p := range paths {
 			for _, t := range p.Info.ExportTypes {
 				if !slices.Contains(exportedTypes, t) {
@@ -131,61 +123,6 @@ func goTypeToTsType(t reflect.Type) (ts string, optional bool) {
 	}
 }
 
-func parseJSONTag(tag string) (name string, omitEmpty bool, ignored bool) {
-	if tag == "" {
-		return "", false, false
-	}
-	if tag == "-" {
-		return "", false, true
-	}
-	parts := strings.Split(tag, ",")
-	name = parts[0]
-	for _, p := range parts[1:] {
-		if p == "omitempty" {
-			omitEmpty = true
-		}
-	}
-	return name, omitEmpty, false
-}
-
 func parseStructFields(t reflect.Type) []StructFieldInfo {
-	for t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-	if t.Kind() != reflect.Struct {
-		return nil
-	}
-
-	var out []StructFieldInfo
-	for i := 0; i < t.NumField(); i++ {
-		sf := t.Field(i)
-
-		// skip unexported (PkgPath != "" means unexported, except embedded sometimes)
-		exported := sf.PkgPath == ""
-		if !exported && !sf.Anonymous {
-			continue
-		}
-
-		raw := sf.Tag.Get("json")
-		name, omitempty, ignored := parseJSONTag(raw)
-
-		jsonName := name
-		if jsonName == "" && !ignored {
-			jsonName = sf.Name
-		}
-
-		fi := StructFieldInfo{
-			GoName:     sf.Name,
-			TSName:     jsonName,
-			JSONName:   jsonName,
-			OmitEmpty:  omitempty,
-			Ignored:    ignored,
-			Exported:   exported,
-			Anonymous:  sf.Anonymous,
-			Type:       sf.Type,
-			TagRawJSON: raw,
-		}
-		out = append(out, fi)
-	}
-	return out
+	return structfields.Parse(t)
 }
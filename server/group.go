@@ -0,0 +1,177 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Group of routes sharing a path prefix and route options (middlewares, tags, export types, ...)
+type Group struct {
+	server *Server
+	prefix string
+	info   RouteInfo
+}
+
+// Create a route group. The prefix and options are merged into every route of the group.
+func (s *Server) Group(prefix string, opts ...RouteOption) *Group {
+	return &Group{
+		server: s,
+		prefix: joinRoute("", prefix),
+		info:   getRouteInfos(opts...),
+	}
+}
+
+// Create a nested route group
+func (g *Group) Group(prefix string, opts ...RouteOption) *Group {
+	return &Group{
+		server: g.server,
+		prefix: joinRoute(g.prefix, prefix),
+		info:   mergeRouteInfo(g.info, getRouteInfos(opts...)),
+	}
+}
+
+// Route for any HTTP method (ignores translation rules)
+func (g *Group) RouteI(method string, route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.server.RouteI(method, joinRoute(g.prefix, route), h, append(opts, withGroupInfo(g.info))...)
+}
+
+// Route for any HTTP method
+func (g *Group) Route(method string, route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.server.Route(method, joinRoute(g.prefix, route), h, append(opts, withGroupInfo(g.info))...)
+}
+
+// GET route (ignores translation rules)
+func (g *Group) GETI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.RouteI(http.MethodGet, route, h, opts...)
+}
+
+// GET route
+func (g *Group) GET(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.Route(http.MethodGet, route, h, opts...)
+}
+
+// POST route (ignores translation rules)
+func (g *Group) POSTI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.RouteI(http.MethodPost, route, h, opts...)
+}
+
+// POST route
+func (g *Group) POST(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.Route(http.MethodPost, route, h, opts...)
+}
+
+// PUT route (ignores translation rules)
+func (g *Group) PUTI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.RouteI(http.MethodPut, route, h, opts...)
+}
+
+// PUT route
+func (g *Group) PUT(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.Route(http.MethodPut, route, h, opts...)
+}
+
+// PATCH route (ignores translation rules)
+func (g *Group) PATCHI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.RouteI(http.MethodPatch, route, h, opts...)
+}
+
+// PATCH route
+func (g *Group) PATCH(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.Route(http.MethodPatch, route, h, opts...)
+}
+
+// DELETE route (ignores translation rules)
+func (g *Group) DELETEI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.RouteI(http.MethodDelete, route, h, opts...)
+}
+
+// DELETE route
+func (g *Group) DELETE(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.Route(http.MethodDelete, route, h, opts...)
+}
+
+// OPTIONS route (ignores translation rules)
+func (g *Group) OPTIONSI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.RouteI(http.MethodOptions, route, h, opts...)
+}
+
+// OPTIONS route
+func (g *Group) OPTIONS(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	g.Route(http.MethodOptions, route, h, opts...)
+}
+
+// join a group prefix and a route. "/" inside a group refers to the prefix itself.
+func joinRoute(prefix string, route string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if route == "/" && prefix != "" {
+		return prefix
+	}
+	if route != "" && !strings.HasPrefix(route, "/") {
+		route = "/" + route
+	}
+	if prefix+route == "" {
+		return "/"
+	}
+	return prefix + route
+}
+
+// merges the group route info into the route info (applied after the route options)
+func withGroupInfo(group RouteInfo) RouteOption {
+	return func(ri *RouteInfo) {
+		*ri = mergeRouteInfo(group, *ri)
+	}
+}
+
+// merge parent and child route infos. Middlewares, tags, params and export types
+// of the parent come first, responses and texts of the child win.
+func mergeRouteInfo(parent RouteInfo, child RouteInfo) RouteInfo {
+	result := newRouteInfo()
+	result.Summary = child.Summary
+	if result.Summary == "" {
+		result.Summary = parent.Summary
+	}
+	result.Description = child.Description
+	if result.Description == "" {
+		result.Description = parent.Description
+	}
+	result.OperationId = child.OperationId
+	for _, tag := range append(slices.Clone(parent.Tags), child.Tags...) {
+		if !slices.Contains(result.Tags, tag) {
+			result.Tags = append(result.Tags, tag)
+		}
+	}
+	result.Middlewares = append(result.Middlewares, parent.Middlewares...)
+	result.Middlewares = append(result.Middlewares, child.Middlewares...)
+	result.Params = append(result.Params, parent.Params...)
+	result.Params = append(result.Params, child.Params...)
+	maps.Copy(result.Responses, parent.Responses)
+	maps.Copy(result.Responses, child.Responses)
+	result.RequestType = child.RequestType
+	maps.Copy(result.ResponseTypes, parent.ResponseTypes)
+	maps.Copy(result.ResponseTypes, child.ResponseTypes)
+	result.WebSocket = child.WebSocket
+	result.Tus = child.Tus
+	result.CORS = child.CORS
+	if result.CORS == nil {
+		result.CORS = parent.CORS
+	}
+	result.Upload = child.Upload
+	if result.Upload == nil {
+		result.Upload = parent.Upload
+	}
+	result.MaxBodyBytes = child.MaxBodyBytes
+	if result.MaxBodyBytes == nil {
+		result.MaxBodyBytes = parent.MaxBodyBytes
+	}
+	result.StrictJSON = parent.StrictJSON || child.StrictJSON
+	result.Hidden = parent.Hidden || child.Hidden
+	result.Security = combineSecurity(parent.Security, child.Security)
+	for _, t := range append(slices.Clone(parent.ExportTypes), child.ExportTypes...) {
+		if !slices.Contains(result.ExportTypes, t) {
+			result.ExportTypes = append(result.ExportTypes, t)
+		}
+	}
+	return result
+}
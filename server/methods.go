@@ -4,50 +4,34 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"unicode"
 )
 
-func getRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method Not Allowed!", http.StatusMethodNotAllowed)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-func postRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method Not Allowed!", http.StatusMethodNotAllowed)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-func putRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPut {
-			http.Error(w, "Method Not Allowed!", http.StatusMethodNotAllowed)
-			return
-		}
+// only lets requests with the given method through (GET routes also accept HEAD)
+func methodRequest(method Method) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if Method(r.Method) != method && !(method == METHOD_GET && r.Method == http.MethodHead) {
+				http.Error(w, "Method Not Allowed!", http.StatusMethodNotAllowed)
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-func deleteRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodDelete {
-			http.Error(w, "Method Not Allowed!", http.StatusMethodNotAllowed)
-			return
+// checks if method is a valid RFC 9110 method token
+func isValidMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for _, c := range method {
+		if c > unicode.MaxASCII || !strings.ContainsRune("!#$%&'*+-.^_`|~", c) && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
 		}
-
-		next.ServeHTTP(w, r)
-	})
+	}
+	return true
 }
 
 func (s *Server) redirectToTranslatedUrl(w http.ResponseWriter, r *http.Request) {
@@ -61,11 +45,11 @@ func (s *Server) redirectToTranslatedUrl(w http.ResponseWriter, r *http.Request)
 
 			for short := range s.Languages {
 				if strings.HasPrefix(lang, short) {
-					http.Redirect(w, r, fmt.Sprintf("/%s%s", short, r.URL.Path), http.StatusFound)
+					http.Redirect(w, r, fmt.Sprintf("%s/%s%s", mountPrefix(r), short, r.URL.Path), http.StatusFound)
 					return
 				}
 			}
 		}
 	}
-	http.Redirect(w, r, fmt.Sprintf("/%s%s", s.DefaultLanguage, r.URL.Path), http.StatusFound)
+	http.Redirect(w, r, fmt.Sprintf("%s/%s%s", mountPrefix(r), s.DefaultLanguage, r.URL.Path), http.StatusFound)
 }
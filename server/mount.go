@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type mountedHandler struct {
+	prefix       string
+	handler      http.Handler
+	middlewares  []func(http.Handler) http.Handler
+	registration RouteRegistration
+}
+
+type mountPrefixKey struct{}
+
+// Mount a sub server or any http.Handler under the given prefix. The prefix is stripped
+// before the request is passed on. Paths of a mounted *Server are part of the OpenAPI
+// description and the exported types of this server.
+func (s *Server) Mount(prefix string, h http.Handler, middlewares ...func(http.Handler) http.Handler) {
+	m := mountedHandler{
+		prefix:      strings.TrimSuffix(joinRoute("", prefix), "/"),
+		handler:     h,
+		middlewares: middlewares,
+	}
+	m.registration = RouteRegistration{
+		Route:    m.prefix + "/",
+		Location: registrationLocation(),
+	}
+	for _, existing := range s.mounts {
+		if existing.prefix == m.prefix {
+			s.registrationErrors = append(s.registrationErrors, &RouteConflictError{
+				First:  existing.registration,
+				Second: m.registration,
+			})
+			return
+		}
+	}
+	for route, paths := range s.Paths {
+		if routeShape(route) == routeShape(m.prefix+"/") {
+			s.registrationErrors = append(s.registrationErrors, &RouteConflictError{
+				First:  paths[0].Registration,
+				Second: m.registration,
+			})
+			return
+		}
+	}
+	if child, ok := h.(*Server); ok {
+		child.parent = s
+	}
+	s.mounts = append(s.mounts, m)
+}
+
+// returns the prefixes a request was mounted under (empty if not mounted)
+func mountPrefix(r *http.Request) string {
+	prefix, _ := r.Context().Value(mountPrefixKey{}).(string)
+	return prefix
+}
+
+func stripMountPrefix(prefix string, h http.Handler) http.Handler {
+	stripped := http.StripPrefix(prefix, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), mountPrefixKey{}, mountPrefix(r)+prefix)
+		stripped.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) setupMounts() error {
+	for _, m := range s.mounts {
+		var h http.Handler
+		translatedChild := false
+		if child, ok := m.handler.(*Server); ok {
+			// the routes of a sub server already come with their own chains
+			err := child.setupHandlersLocked()
+			if err != nil {
+				return fmt.Errorf("mount %s: %w", m.prefix, err)
+			}
+			translatedChild = child.TranslationsEnabled
+			h = s.chainWithoutBuiltins(child, m.middlewares...)
+		} else {
+			h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s.chain(m.handler, m.middlewares...).ServeHTTP(w, r)
+			})
+		}
+
+		err := handlePattern(s.mux, m.prefix+"/", stripMountPrefix(m.prefix, h))
+		if err != nil {
+			return err
+		}
+
+		// a sub server with translations handles the language prefixes by itself,
+		// everything else is reachable below every language prefix as well
+		if s.TranslationsEnabled && !translatedChild {
+			for short := range s.Languages {
+				prefix := fmt.Sprintf("/%s%s", short, m.prefix)
+				err := handlePattern(s.mux, prefix+"/", stripMountPrefix(prefix, h))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// all paths of this server including the paths of mounted sub servers
+func (s *Server) allPaths() map[string][]ServerPath {
+	result := maps.Clone(s.Paths)
+	for _, m := range s.mounts {
+		child, ok := m.handler.(*Server)
+		if !ok {
+			continue
+		}
+		for route, paths := range child.allPaths() {
+			route = m.prefix + route
+			for _, p := range paths {
+				p.Route = m.prefix + p.Route
+				result[route] = append(slices.Clip(result[route]), p)
+			}
+		}
+	}
+	return result
+}
+
+// security schemes of the server and its mounted servers (the server's own win)
+func (s *Server) allSecuritySchemes() map[string]SecurityScheme {
+	result := map[string]SecurityScheme{}
+	for _, m := range s.mounts {
+		child, ok := m.handler.(*Server)
+		if !ok {
+			continue
+		}
+		maps.Copy(result, child.allSecuritySchemes())
+	}
+	maps.Copy(result, s.SecuritySchemes)
+	return result
+}
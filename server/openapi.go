@@ -3,9 +3,14 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 type OpenAPIDescription struct {
@@ -17,12 +22,28 @@ type OpenAPIDescription struct {
 }
 
 type OpenAPIServer struct {
-	Url string `json:"url"`
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
 }
 
 type OpenAPIDescriptionInfo struct {
-	Title   string `json:"title"`
-	Version string `json:"version"`
+	Title       string          `json:"title"`
+	Version     string          `json:"version"`
+	Description string          `json:"description,omitempty"`
+	Contact     *OpenAPIContact `json:"contact,omitempty"`
+	License     *OpenAPILicense `json:"license,omitempty"`
+}
+
+type OpenAPIContact struct {
+	Name  string `json:"name,omitempty"`
+	Url   string `json:"url,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type OpenAPILicense struct {
+	Name       string `json:"name"`
+	Identifier string `json:"identifier,omitempty"`
+	Url        string `json:"url,omitempty"`
 }
 
 type OpenAPIPath struct {
@@ -30,12 +51,25 @@ type OpenAPIPath struct {
 	Description string                     `json:"description"`
 	Tags        []string                   `json:"tags"`
 	OperationId string                     `json:"operationId"`
+	Parameters  []OpenAPIParam             `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
 	Responses   map[string]OpenAPIResponse `json:"responses"`
 	Security    []map[string]any           `json:"security"`
 }
 
 type OpenAPIResponse struct {
-	Description string `json:"description"`
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIRequestBody struct {
+	Description string                      `json:"description,omitempty"`
+	Required    bool                        `json:"required"`
+	Content     map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema map[string]any `json:"schema"`
 }
 
 type OpenAPIParam struct {
@@ -46,141 +80,233 @@ type OpenAPIParam struct {
 	Schema      map[string]any `json:"schema"`
 }
 
+var openAPIMethods = []Method{
+	METHOD_GET,
+	METHOD_PUT,
+	METHOD_POST,
+	METHOD_DELETE,
+	METHOD_OPTIONS,
+	METHOD_HEAD,
+	METHOD_PATCH,
+	http.MethodTrace,
+}
+
+func statusFromCode(code string) int {
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return 0
+	}
+	return status
+}
+
+// names of the wildcards of a route ("{path...}" is "path", "{$}" is left out)
 func getRouteParams(route string) []string {
 	result := []string{}
-	routeSplit := strings.SplitSeq(route, "")
-	status := 0
-	readingStatusName := ""
-	for char := range routeSplit {
-		if status == 0 {
-			if char == "{" {
-				status = 1
-			}
-		} else if status == 1 {
-			if char == "}" {
-				status = 0
-				// fmt.Println("status name: ", readingStatusName)
-				result = append(result, readingStatusName)
-				readingStatusName = ""
-			} else {
-				readingStatusName += char
-			}
+	rest := route
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			break
+		}
+		name := strings.TrimSuffix(rest[start+1:start+end], "...")
+		if name != "$" && name != "" {
+			result = append(result, name)
 		}
+		rest = rest[start+end+1:]
 	}
 	return result
 }
 
-func createRoutePaths(path *ServerPath, operationId string) map[string]any {
-	routePaths := make(map[string]any)
+// OpenAPI path of a ServeMux pattern: "{path...}" becomes "{path}", "{$}" is removed
+func openAPIRoute(route string) string {
+	route = strings.ReplaceAll(route, "{$}", "")
+	return strings.ReplaceAll(route, "...}", "}")
+}
 
-	// read parameters out of route and if necessary create OpenAPIParam config
-	routeParams := getRouteParams(path.Route)
-	if len(routeParams) > 0 || len(path.Info.Params) > 0 {
-		parameters := []OpenAPIParam{}
-		for _, param := range routeParams {
-			if slices.ContainsFunc(path.Info.Params, func(e OpenAPIParam) bool {
-				return e.Name == param
-			}) {
-				continue
-			}
-			paramSchema := map[string]any{}
-			paramSchema["type"] = "string"
-			thisParam := OpenAPIParam{
-				Description: "",
-				Name:        param,
-				In:          "path",
-				Required:    true,
-				Schema:      paramSchema,
-			}
-			parameters = append(parameters, thisParam)
+// stable operationId from method and route, e.g. GET /users/{id} is getUsersById
+func defaultOperationId(method Method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(string(method)))
+	for segment := range strings.SplitSeq(openAPIRoute(route), "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			b.WriteString("By")
+			segment = strings.TrimSuffix(name, "}")
+		}
+		for word := range strings.FieldsFuncSeq(segment, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// path item of a route: the path parameters shared by all operations and one operation
+// per method. operationIds holds the ids used so far and keeps them unique.
+func createPathItem(route string, serverPaths []ServerPath, schemas *openAPISchemas, operationIds map[string]bool) map[string]any {
+	serverPaths = slices.Clone(serverPaths)
+	slices.SortFunc(serverPaths, func(a ServerPath, b ServerPath) int {
+		return strings.Compare(string(a.Method), string(b.Method))
+	})
+
+	pathItem := map[string]any{}
+	parameters := []OpenAPIParam{}
+	for _, name := range getRouteParams(route) {
+		param := OpenAPIParam{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   map[string]any{"type": "string"},
 		}
-		for _, param := range path.Info.Params {
-			thisParam := OpenAPIParam{
-				Description: param.Description,
-				Name:        param.Name,
-				In:          param.In,
-				Required:    param.Required,
-				Schema:      param.Schema,
+		// documented path params (e.g. of typed handlers) replace the default
+		for _, path := range serverPaths {
+			index := slices.IndexFunc(path.Info.Params, func(p OpenAPIParam) bool {
+				return p.In == "path" && p.Name == name
+			})
+			if index >= 0 {
+				param = path.Info.Params[index]
+				param.Required = true
+				break
 			}
-			parameters = append(parameters, thisParam)
 		}
-		routePaths["parameters"] = parameters
-	}
-
-	switch path.Method {
-	case METHOD_GET:
-		routePaths["get"] = OpenAPIPath{
-			Summary:     path.Info.Summary,
-			Description: path.Info.Description,
-			Tags:        path.Info.Tags,
-			OperationId: operationId,
-			Responses:   path.Info.Responses,
-			Security:    []map[string]any{},
+		parameters = append(parameters, param)
+	}
+	if len(parameters) > 0 {
+		pathItem["parameters"] = parameters
+	}
+
+	for _, path := range serverPaths {
+		// OpenAPI 3.1 only knows the standard methods, custom ones are left out
+		if !slices.Contains(openAPIMethods, path.Method) {
+			continue
+		}
+		operationId := path.Info.OperationId
+		if operationId == "" {
+			operationId = defaultOperationId(path.Method, route)
 		}
-	case METHOD_POST:
-		routePaths["post"] = OpenAPIPath{
-			Summary:     path.Info.Summary,
-			Description: path.Info.Description,
-			Tags:        path.Info.Tags,
-			OperationId: operationId,
-			Responses:   path.Info.Responses,
-			Security:    []map[string]any{},
+		unique := operationId
+		for i := 2; operationIds[unique]; i++ {
+			unique = fmt.Sprintf("%s%d", operationId, i)
 		}
-	case METHOD_PUT:
-		routePaths["put"] = OpenAPIPath{
-			Summary:     path.Info.Summary,
-			Description: path.Info.Description,
-			Tags:        path.Info.Tags,
-			OperationId: operationId,
-			Responses:   path.Info.Responses,
-			Security:    []map[string]any{},
+		operationIds[unique] = true
+
+		pathItem[strings.ToLower(string(path.Method))] = createOperation(&path, unique, schemas)
+	}
+	return pathItem
+}
+
+// operation of a route method, path parameters are documented by the path item
+func createOperation(path *ServerPath, operationId string, schemas *openAPISchemas) OpenAPIPath {
+	operation := OpenAPIPath{
+		Summary:     path.Info.Summary,
+		Description: path.Info.Description,
+		Tags:        path.Info.Tags,
+		OperationId: operationId,
+		Responses:   path.Info.Responses,
+		Security:    []map[string]any{},
+	}
+	for _, requirement := range path.Info.Security {
+		security := map[string]any{}
+		for name, scopes := range requirement {
+			security[name] = scopes
 		}
-	case METHOD_DELETE:
-		routePaths["delete"] = OpenAPIPath{
-			Summary:     path.Info.Summary,
-			Description: path.Info.Description,
-			Tags:        path.Info.Tags,
-			OperationId: operationId,
-			Responses:   path.Info.Responses,
-			Security:    []map[string]any{},
+		operation.Security = append(operation.Security, security)
+	}
+	if len(path.Info.Security) > 0 && operation.Responses["401"].Description == "" {
+		responses := map[string]OpenAPIResponse{}
+		maps.Copy(responses, operation.Responses)
+		operation.Responses = responses
+		operation.Responses["401"] = OpenAPIResponse{Description: "Unauthorized"}
+	}
+	for _, param := range path.Info.Params {
+		if param.In != "path" {
+			operation.Parameters = append(operation.Parameters, param)
 		}
 	}
-	return routePaths
+	addRouteTypeSchemas(&operation, path.Info, schemas)
+	return operation
 }
 
-func (s *Server) CreateOpenAPIJson(port string) {
+// the OpenAPI description of all routes (including mounted servers). Paths are sorted and
+// operationIds derived from the routes, so the output is the same on every run.
+func (s *Server) openAPIDescription() OpenAPIDescription {
+	info := s.OpenAPIInfo
 	openApiObj := OpenAPIDescription{
 		Version: "3.1.0",
 		Info: OpenAPIDescriptionInfo{
-			Title:   "Title Desc",
-			Version: "1.0",
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+			Contact:     info.Contact,
+			License:     info.License,
 		},
-		Servers:    []OpenAPIServer{},
+		Servers:    append([]OpenAPIServer{}, info.Servers...),
 		Paths:      make(map[string]map[string]any),
 		Components: make(map[string]any),
 	}
+	if openApiObj.Info.Title == "" {
+		openApiObj.Info.Title = "Title Desc"
+	}
+	if openApiObj.Info.Version == "" {
+		openApiObj.Info.Version = "1.0"
+	}
 
-	openApiObj.Servers = append(openApiObj.Servers, OpenAPIServer{
-		Url: "http://localhost:" + port,
-	})
-
-	operationId := 1
-	parameters := make(map[string]OpenAPIParam)
-	for _, paths := range s.Paths {
-		for _, path := range paths {
-			routePaths := createRoutePaths(&path, fmt.Sprintf("%v", operationId))
-			openApiObj.Paths[path.Route] = routePaths
-			operationId++
+	allPaths := s.allPaths()
+	routes := slices.Sorted(maps.Keys(allPaths))
+	schemas := newOpenAPISchemas()
+	operationIds := map[string]bool{}
+	for _, route := range routes {
+		paths := slices.DeleteFunc(slices.Clone(allPaths[route]), func(p ServerPath) bool {
+			return p.Info.Hidden
+		})
+		if len(paths) == 0 {
+			continue
 		}
+		openApiObj.Paths[openAPIRoute(route)] = createPathItem(route, paths, schemas, operationIds)
+	}
+	openApiObj.Components["schemas"] = schemas.schemas
+	if securitySchemes := s.allSecuritySchemes(); len(securitySchemes) > 0 {
+		openApiObj.Components["securitySchemes"] = securitySchemes
+	}
+	return openApiObj
+}
+
+// Write openapi.json with a localhost server for the port (unless servers are set with
+// WithOpenAPIInfo). Panics on errors, see WriteOpenAPI.
+func (s *Server) CreateOpenAPIJson(port string) {
+	openApiObj := s.openAPIDescription()
+	if len(openApiObj.Servers) == 0 {
+		openApiObj.Servers = append(openApiObj.Servers, OpenAPIServer{
+			Url: "http://localhost:" + port,
+		})
 	}
-	openApiObj.Components["parameters"] = parameters
 
 	m, err := json.MarshalIndent(openApiObj, "", "  ")
 	if err != nil {
 		panic(err)
 	}
-	err = os.WriteFile("openapi.json", m, 0777)
+	err = os.WriteFile("openapi.json", m, 0644)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// Write the OpenAPI description to filename, as YAML for .yaml/.yml files and JSON otherwise
+func (s *Server) WriteOpenAPI(filename string) error {
+	data, err := json.MarshalIndent(s.openAPIDescription(), "", "  ")
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		data, err = jsonToYAML(data)
+		if err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filename, data, 0644)
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/loissascha/go-http-server/internal/openapidoc"
+	"github.com/loissascha/go-logger/logger"
+)
+
+// Answer every operation of an OpenAPI 3 document (JSON or YAML) with example data: the
+// examples of the lowest documented 2xx response, or values built from its schema. Clients
+// can ask for another documented status with "Prefer: code=404". Routes registered before
+// or after replace the mocks with the same route shape and method, mocks never conflict.
+func (s *Server) MockOpenAPI(spec []byte) error {
+	doc, err := openapidoc.Parse(spec)
+	if err != nil {
+		return err
+	}
+	paths := openapidoc.Object(doc["paths"])
+	for _, path := range openapidoc.Keys(paths) {
+		route, ok := mockRoute(path)
+		if !ok {
+			logger.Warning(nil, "Not mocking {path}: not a valid route pattern", path)
+			continue
+		}
+		item := openapidoc.Resolve(doc, openapidoc.Object(paths[path]))
+		for _, method := range openapidoc.Methods {
+			operation := openapidoc.Object(item[method])
+			if operation == nil {
+				continue
+			}
+			m := &mockOperation{doc: doc, operation: operation}
+			s.addPath(route, ServerPath{
+				Route:   route,
+				Method:  Method(strings.ToUpper(method)),
+				Info:    mockRouteInfo(doc, item, operation),
+				Handler: m.serve,
+				mock:    true,
+			})
+		}
+	}
+	return nil
+}
+
+var mockRouteSegment = regexp.MustCompile(`^(\{[A-Za-z_][A-Za-z0-9_]*\}|[^{}]*)$`)
+
+// ServeMux pattern of an OpenAPI path, wildcards have to be whole segments. Trailing
+// slashes match exactly ("/users/" is "/users/{$}").
+func mockRoute(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return "", false
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if !mockRouteSegment.MatchString(segment) {
+			return "", false
+		}
+	}
+	if strings.HasSuffix(path, "/") {
+		path += "{$}"
+	}
+	return path, true
+}
+
+// A real route replaces a mock with the same route shape and method. Returns p with the
+// route it is added to and false if p is a mock that isn't needed because the method is
+// registered already. Mocks join routes of the same shape with other wildcard names, and a
+// real route takes over the remaining mocks of such a route, so the patterns never conflict.
+func (s *Server) replaceMock(p ServerPath) (ServerPath, bool) {
+	route := p.Route
+	shape := routeShape(route)
+	for existingRoute, paths := range s.Paths {
+		if existingRoute == route && !p.mock || routeShape(existingRoute) != shape {
+			continue
+		}
+		if p.mock {
+			if slices.ContainsFunc(paths, func(existing ServerPath) bool { return existing.Method == p.Method }) {
+				return p, false
+			}
+			return renameWildcards(p, existingRoute), true
+		}
+		if slices.ContainsFunc(paths, func(existing ServerPath) bool { return !existing.mock }) {
+			// a real route with other wildcard names stays as it is, patternConflict
+			// reports p as conflict
+			continue
+		}
+		paths = slices.DeleteFunc(slices.Clone(paths), func(existing ServerPath) bool {
+			return existing.Method == p.Method
+		})
+		delete(s.Paths, existingRoute)
+		for _, mock := range paths {
+			s.Paths[route] = append(s.Paths[route], renameWildcards(mock, route))
+		}
+	}
+	// same route: drop a mock of the method
+	s.Paths[route] = slices.DeleteFunc(s.Paths[route], func(existing ServerPath) bool {
+		return !p.mock && existing.mock && existing.Method == p.Method
+	})
+	if len(s.Paths[route]) == 0 {
+		delete(s.Paths, route)
+	}
+	return p, true
+}
+
+// mock moved to a route of the same shape, its path params take the wildcard names of route
+func renameWildcards(p ServerPath, route string) ServerPath {
+	names := map[string]string{}
+	newNames := wildcardNames(route)
+	for i, name := range wildcardNames(p.Route) {
+		names[name] = newNames[i]
+	}
+	p.Route = route
+	p.Info.Params = slices.Clone(p.Info.Params)
+	for i, param := range p.Info.Params {
+		if newName, found := names[param.Name]; found && param.In == "path" {
+			p.Info.Params[i].Name = newName
+		}
+	}
+	return p
+}
+
+func wildcardNames(route string) []string {
+	names := []string{}
+	for _, match := range routeWildcard.FindAllStringSubmatch(route, -1) {
+		names = append(names, strings.TrimSuffix(match[1], "..."))
+	}
+	return names
+}
+
+var routeWildcard = regexp.MustCompile(`\{([^}]*)\}`)
+
+// documentation of a mocked operation, schemas are inlined as the components of the
+// document aren't part of the server's description
+func mockRouteInfo(doc map[string]any, item map[string]any, operation map[string]any) RouteInfo {
+	info := newRouteInfo()
+	info.Summary, _ = operation["summary"].(string)
+	info.Description, _ = operation["description"].(string)
+	info.OperationId, _ = operation["operationId"].(string)
+	for _, tag := range openapidoc.Array(operation["tags"]) {
+		if tag, ok := tag.(string); ok {
+			info.Tags = append(info.Tags, tag)
+		}
+	}
+
+	for _, raw := range append(openapidoc.Array(item["parameters"]), openapidoc.Array(operation["parameters"])...) {
+		param := openapidoc.Resolve(doc, openapidoc.Object(raw))
+		p := OpenAPIParam{Schema: openapidoc.Inline(doc, openapidoc.Object(param["schema"]), 8)}
+		p.Name, _ = param["name"].(string)
+		p.In, _ = param["in"].(string)
+		p.Required, _ = param["required"].(bool)
+		p.Description, _ = param["description"].(string)
+		info.Params = slices.DeleteFunc(info.Params, func(existing OpenAPIParam) bool {
+			return existing.Name == p.Name && existing.In == p.In
+		})
+		info.Params = append(info.Params, p)
+	}
+
+	responses := openapidoc.Object(operation["responses"])
+	for _, code := range openapidoc.Keys(responses) {
+		if statusFromCode(code) == 0 {
+			continue
+		}
+		response := openapidoc.Resolve(doc, openapidoc.Object(responses[code]))
+		r := OpenAPIResponse{}
+		r.Description, _ = response["description"].(string)
+		content := openapidoc.Object(response["content"])
+		for _, mediaType := range openapidoc.Keys(content) {
+			if r.Content == nil {
+				r.Content = map[string]OpenAPIMediaType{}
+			}
+			schema := openapidoc.Object(openapidoc.Object(content[mediaType])["schema"])
+			r.Content[mediaType] = OpenAPIMediaType{Schema: openapidoc.Inline(doc, schema, 8)}
+		}
+		info.Responses[code] = r
+	}
+	return info
+}
+
+type mockOperation struct {
+	doc       map[string]any
+	operation map[string]any
+}
+
+func (m *mockOperation) serve(w http.ResponseWriter, r *http.Request) {
+	responses := openapidoc.Object(m.operation["responses"])
+	code := mockStatusCode(responses, r.Header.Get("Prefer"))
+	status := statusFromCode(code)
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := openapidoc.Resolve(m.doc, openapidoc.Object(responses[code]))
+	content := openapidoc.Object(response["content"])
+	if len(content) == 0 {
+		w.WriteHeader(status)
+		return
+	}
+	mediaType := "application/json"
+	if content[mediaType] == nil {
+		mediaType = openapidoc.Keys(content)[0]
+	}
+	value := m.example(openapidoc.Object(content[mediaType]))
+
+	if text, ok := value.(string); ok && !isJSONMediaType(mediaType) {
+		w.Header().Set("Content-Type", mediaType)
+		w.WriteHeader(status)
+		w.Write([]byte(text))
+		return
+	}
+	if !isJSONMediaType(mediaType) {
+		mediaType = "application/json"
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// example of a media type: example, the first of examples or one built from the schema
+func (m *mockOperation) example(media map[string]any) any {
+	if value, found := media["example"]; found {
+		return value
+	}
+	examples := openapidoc.Object(media["examples"])
+	if keys := openapidoc.Keys(examples); len(keys) > 0 {
+		return openapidoc.Resolve(m.doc, openapidoc.Object(examples[keys[0]]))["value"]
+	}
+	return openapidoc.Example(m.doc, openapidoc.Object(media["schema"]))
+}
+
+// response code to mock: the one asked for with "Prefer: code=XXX", the lowest 2xx,
+// default or the lowest documented one
+func mockStatusCode(responses map[string]any, prefer string) string {
+	for part := range strings.FieldsFuncSeq(prefer, func(r rune) bool { return r == ',' || r == ';' }) {
+		if code, found := strings.CutPrefix(strings.TrimSpace(part), "code="); found && responses[code] != nil {
+			return code
+		}
+	}
+	codes := openapidoc.Keys(responses)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			return code
+		}
+	}
+	if responses["default"] != nil || len(codes) == 0 {
+		return "default"
+	}
+	return codes[0]
+}
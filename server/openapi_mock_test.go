@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockSpec = `openapi: 3.1.0
+info:
+  title: Pets
+  version: "1.0"
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: Pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Pet"
+    post:
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              example: {id: 7, name: Rex}
+        "422":
+          description: Invalid
+          content:
+            application/json:
+              examples:
+                missingName:
+                  value: {error: name is required}
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, schema: {type: integer}}
+    get:
+      responses:
+        "200":
+          description: Pet
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+    delete:
+      responses:
+        "204":
+          description: Deleted
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id: {type: integer, minimum: 1}
+        name: {type: string, example: Rex}
+        born: {type: string, format: date}
+`
+
+func TestMockOpenAPI(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GET("/pets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real " + r.PathValue("id")))
+	})
+	assert.NoError(t, s.MockOpenAPI([]byte(mockSpec)))
+	assert.NoError(t, s.Validate())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	request := func(method string, path string, prefer string) (*http.Response, string) {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		assert.NoError(t, err)
+		if prefer != "" {
+			req.Header.Set("Prefer", prefer)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := request(http.MethodGet, "/pets", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var pets []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(body), &pets))
+	assert.Equal(t, []map[string]any{{"id": float64(1), "name": "Rex", "born": "2024-01-01"}}, pets)
+
+	resp, body = request(http.MethodPost, "/pets", "")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.JSONEq(t, `{"id": 7, "name": "Rex"}`, body)
+
+	resp, body = request(http.MethodPost, "/pets", "code=422")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.JSONEq(t, `{"error": "name is required"}`, body)
+
+	// undocumented codes fall back to the default choice
+	resp, _ = request(http.MethodPost, "/pets", "code=500")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// the real route replaced the mock with the same shape
+	resp, body = request(http.MethodGet, "/pets/3", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "real 3", body)
+
+	resp, body = request(http.MethodDelete, "/pets/3", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, body)
+
+	// the remaining mock joined the real route with its wildcard name
+	assert.NotContains(t, s.Paths, "/pets/{petId}")
+	assert.Len(t, s.Paths["/pets/{id}"], 2)
+	for _, p := range s.Paths["/pets/{id}"] {
+		if p.Method == METHOD_DELETE {
+			assert.Equal(t, "/pets/{id}", p.Route)
+			assert.Equal(t, "id", p.Info.Params[0].Name)
+		}
+	}
+}
+
+func TestMockOpenAPIReplacedLater(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	assert.NoError(t, s.MockOpenAPI([]byte(mockSpec)))
+	s.POST("/pets", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	assert.NoError(t, s.Validate())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/pets", "application/json", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Len(t, s.Paths["/pets"], 2)
+
+	// a real route with other wildcard names takes over the mocks of the route
+	s.DELETE("/pets/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	assert.NoError(t, s.Validate())
+	assert.NotContains(t, s.Paths, "/pets/{petId}")
+	assert.Len(t, s.Paths["/pets/{id}"], 2)
+}
+
+func TestMockOpenAPIWildcardConflict(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	assert.NoError(t, s.MockOpenAPI([]byte(mockSpec)))
+	s.GET("/pets/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	assert.NoError(t, s.Validate())
+
+	// two real routes only differing in wildcard names conflict, the mocks stay
+	s.DELETE("/pets/{key}", func(w http.ResponseWriter, r *http.Request) {})
+	var conflict *RouteConflictError
+	assert.ErrorAs(t, s.Validate(), &conflict)
+	assert.Equal(t, "/pets/{id}", conflict.First.Route)
+	assert.Equal(t, "/pets/{key}", conflict.Second.Route)
+	assert.NotContains(t, s.Paths, "/pets/{key}")
+	assert.Len(t, s.Paths["/pets/{id}"], 2)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/pets/1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestMockOpenAPIErrors(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	assert.Error(t, s.MockOpenAPI([]byte(`swagger: "2.0"`)))
+	assert.Error(t, s.MockOpenAPI([]byte(`{"openapi": `)))
+}
+
+func TestMockRoute(t *testing.T) {
+	route, ok := mockRoute("/users/{id}/")
+	assert.True(t, ok)
+	assert.Equal(t, "/users/{id}/{$}", route)
+	_, ok = mockRoute("/files/{name}.json")
+	assert.False(t, ok)
+	assert.Equal(t, routeShape("/users/{id}"), routeShape("/users/{userId}"))
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/loissascha/go-http-server/internal/structfields"
+)
+
+// OpenAPI parameters for the struct fields tagged with path, query or header
+func structParams(t reflect.Type) []OpenAPIParam {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	params := []OpenAPIParam{}
+	for _, location := range []string{"path", "query", "header"} {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, ok := sf.Tag.Lookup(location)
+			if !ok || !sf.IsExported() {
+				continue
+			}
+			params = append(params, OpenAPIParam{
+				Name:     name,
+				In:       location,
+				Required: location == "path",
+				Schema:   typeToSchema(sf.Type),
+			})
+		}
+	}
+	return params
+}
+
+// bound from the request itself or the form instead of the JSON body
+func isRequestValueField(sf reflect.StructField) bool {
+	for _, location := range []string{"path", "query", "header", "form"} {
+		if _, ok := sf.Tag.Lookup(location); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checks if the struct has fields that are read from the JSON body
+func hasBodyFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, info := range parseStructFields(t) {
+		sf, _ := t.FieldByName(info.GoName)
+		if !info.Ignored && !isRequestValueField(sf) {
+			return true
+		}
+	}
+	return false
+}
+
+// object schema of the fields tagged with form, UploadedFile fields are binary strings
+func formSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": map[string]any{"type": "string", "format": "binary"},
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, ok := sf.Tag.Lookup("form")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		switch sf.Type {
+		case uploadedFileType, reflect.PointerTo(uploadedFileType):
+			properties[name] = map[string]any{"type": "string", "format": "binary"}
+		case reflect.SliceOf(uploadedFileType):
+			properties[name] = map[string]any{"type": "array", "items": map[string]any{"type": "string", "format": "binary"}}
+		default:
+			properties[name] = typeToSchema(sf.Type)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// checks if the struct has fields tagged with form
+func hasFormFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("form"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON schemas of Go types (see goTypeToTsType for the TS counterpart). Named structs are
+// collected for components/schemas and referenced with $ref, without components (nil maps)
+// everything is inlined.
+type openAPISchemas struct {
+	schemas map[string]map[string]any
+	names   map[reflect.Type]string
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{
+		schemas: map[string]map[string]any{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// inline JSON schema of a Go type (parameters, form fields)
+func typeToSchema(t reflect.Type) map[string]any {
+	return (&openAPISchemas{}).schema(t)
+}
+
+// schema of t, pointers are nullable
+func (c *openAPISchemas) schema(t reflect.Type) map[string]any {
+	return c.schemaVisited(t, []reflect.Type{})
+}
+
+func (c *openAPISchemas) schemaVisited(t reflect.Type, visited []reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		return nullableSchema(c.schemaVisited(t, visited))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": c.schemaVisited(t.Elem(), visited)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": c.schemaVisited(t.Elem(), visited)}
+	case reflect.Struct:
+		if t.PkgPath() == "time" && t.Name() == "Time" {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if c.schemas != nil && t.Name() != "" {
+			return c.ref(t)
+		}
+		if slices.Contains(visited, t) {
+			// recursive type
+			return map[string]any{"type": "object"}
+		}
+		return c.structSchema(t, append(visited, t))
+	default:
+		return map[string]any{}
+	}
+}
+
+// adds the struct to the components (once) and references it
+func (c *openAPISchemas) ref(t reflect.Type) map[string]any {
+	name, found := c.names[t]
+	if !found {
+		name = c.componentName(t)
+		c.names[t] = name
+		// registered before the properties are built, so recursive types reference themselves
+		c.schemas[name] = map[string]any{}
+		c.schemas[name] = c.structSchema(t, []reflect.Type{t})
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// the type name, prefixed with the package for types with the same name from different packages
+func (c *openAPISchemas) componentName(t reflect.Type) string {
+	taken := func(name string) bool {
+		_, found := c.schemas[name]
+		return found
+	}
+	name := sanitizeComponentName(t.Name())
+	if !taken(name) {
+		return name
+	}
+	name = sanitizeComponentName(path.Base(t.PkgPath()) + "." + t.Name())
+	base := name
+	for i := 2; taken(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return name
+}
+
+// component names may only contain A-Z a-z 0-9 . - _ (generic type arguments lose their package path)
+func sanitizeComponentName(name string) string {
+	var b strings.Builder
+	segment := strings.Builder{}
+	flush := func() {
+		s := segment.String()
+		if i := strings.LastIndex(s, "/"); i >= 0 {
+			s = s[i+1:]
+		}
+		b.WriteString(s)
+		segment.Reset()
+	}
+	for _, r := range name {
+		switch {
+		case r == '[' || r == ']' || r == ',' || r == ' ' || r == '*':
+			flush()
+			b.WriteByte('_')
+		case r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_' || r == '/'):
+			segment.WriteRune(r)
+		default:
+			segment.WriteByte('_')
+		}
+	}
+	flush()
+	return strings.Trim(b.String(), "_")
+}
+
+func (c *openAPISchemas) structSchema(t reflect.Type, visited []reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	c.addStructProperties(t, properties, &required, visited)
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// adds the JSON fields of t, fields of embedded structs are promoted like encoding/json does
+func (c *openAPISchemas) addStructProperties(t reflect.Type, properties map[string]any, required *[]string, visited []reflect.Type) {
+	for _, info := range parseStructFields(t) {
+		sf, _ := t.FieldByName(info.GoName)
+		if info.Ignored || isRequestValueField(sf) {
+			continue
+		}
+		if name, _, _ := structfields.ParseJSONTag(info.TagRawJSON); info.Anonymous && name == "" {
+			embedded := info.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				c.addStructProperties(embedded, properties, required, visited)
+				continue
+			}
+		}
+		if !info.Exported {
+			continue
+		}
+		properties[info.JSONName] = c.schemaVisited(info.Type, visited)
+		// missing JSON fields decode to their zero value, so only validate:"required" makes them required
+		if rules, _ := parseValidationTag(sf.Tag.Get("validate"), info.JSONName); hasValidationRule(rules, "required") {
+			*required = append(*required, info.JSONName)
+		}
+	}
+}
+
+// allows null in addition to the schema (OpenAPI 3.1 type arrays)
+func nullableSchema(schema map[string]any) map[string]any {
+	if typeName, ok := schema["type"].(string); ok {
+		nullable := maps.Clone(schema)
+		nullable["type"] = []string{typeName, "null"}
+		return nullable
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
+
+// request body and response contents of the route types
+func addRouteTypeSchemas(operation *OpenAPIPath, info RouteInfo, schemas *openAPISchemas) {
+	if info.Upload != nil {
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				"multipart/form-data": {Schema: formSchema(info.RequestType)},
+			},
+		}
+	} else if info.RequestType != nil && hasFormFields(info.RequestType) {
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				"application/x-www-form-urlencoded": {Schema: formSchema(info.RequestType)},
+			},
+		}
+	} else if info.RequestType != nil {
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				"application/json": {Schema: schemas.schema(info.RequestType)},
+			},
+		}
+	}
+	if len(info.ResponseTypes) == 0 {
+		return
+	}
+	responses := map[string]OpenAPIResponse{}
+	for code, response := range info.Responses {
+		responses[code] = response
+	}
+	for code, t := range info.ResponseTypes {
+		response, found := responses[code]
+		if !found {
+			response = OpenAPIResponse{Description: http.StatusText(statusFromCode(code))}
+		}
+		response.Content = map[string]OpenAPIMediaType{
+			"application/json": {Schema: schemas.schema(t)},
+		}
+		responses[code] = response
+	}
+	operation.Responses = responses
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// General information of the OpenAPI description (see WithOpenAPIInfo)
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Contact     *OpenAPIContact
+	License     *OpenAPILicense
+	Servers     []OpenAPIServer
+}
+
+type OpenAPIServeConfig struct {
+	// route of the YAML spec, "" disables it
+	YAMLRoute string
+	// route of the documentation page, "" disables it
+	DocsRoute string
+}
+
+type OpenAPIServeOption func(*OpenAPIServeConfig)
+
+// Serve the YAML spec at route (defaults to the JSON route with .yaml, "" disables it)
+func WithOpenAPIYAML(route string) OpenAPIServeOption {
+	return func(c *OpenAPIServeConfig) {
+		c.YAMLRoute = route
+	}
+}
+
+// Serve the documentation page at route (defaults to /docs, "" disables it)
+func WithOpenAPIDocs(route string) OpenAPIServeOption {
+	return func(c *OpenAPIServeConfig) {
+		c.DocsRoute = route
+	}
+}
+
+//go:embed openapi_docs.html
+var openAPIDocsHTML string
+
+var openAPIDocsTemplate = template.Must(template.New("docs").Parse(openAPIDocsHTML))
+
+// Serve the OpenAPI description of the server as JSON at route, as YAML and an offline
+// documentation page. The spec is built on the first request, so routes registered
+// after ServeOpenAPI are part of it. The routes themselves are hidden from the spec.
+func (s *Server) ServeOpenAPI(route string, opts ...OpenAPIServeOption) {
+	route = joinRoute("", route)
+	config := OpenAPIServeConfig{
+		YAMLRoute: strings.TrimSuffix(route, ".json") + ".yaml",
+		DocsRoute: "/docs",
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	spec := s.spec
+
+	s.GETI(route, func(w http.ResponseWriter, r *http.Request) {
+		spec.serve(w, r, false)
+	}, WithHidden())
+	if config.YAMLRoute != "" {
+		s.GETI(joinRoute("", config.YAMLRoute), func(w http.ResponseWriter, r *http.Request) {
+			spec.serve(w, r, true)
+		}, WithHidden())
+	}
+	if config.DocsRoute != "" {
+		s.GETI(joinRoute("", config.DocsRoute), func(w http.ResponseWriter, r *http.Request) {
+			title := s.OpenAPIInfo.Title
+			if title == "" {
+				title = "API Documentation"
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			err := openAPIDocsTemplate.Execute(w, map[string]string{
+				"Title":    title,
+				"SpecURL":  mountPrefix(r) + route,
+				"BasePath": mountPrefix(r),
+			})
+			if err != nil {
+				s.writeError(w, r, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			}
+		}, WithHidden())
+	}
+}
+
+// JSON, YAML and the validation document of the description, built once
+type openAPISpec struct {
+	server *Server
+	once   sync.Once
+	json   []byte
+	yaml   []byte
+	doc    *openAPIDocument
+	err    error
+}
+
+func (spec *openAPISpec) build() error {
+	spec.once.Do(func() {
+		spec.json, spec.err = json.MarshalIndent(spec.server.openAPIDescription(), "", "  ")
+		if spec.err != nil {
+			return
+		}
+		spec.yaml, spec.err = jsonToYAML(spec.json)
+		if spec.err != nil {
+			return
+		}
+		spec.doc, spec.err = newOpenAPIDocument(spec.json)
+	})
+	return spec.err
+}
+
+func (spec *openAPISpec) document() (*openAPIDocument, error) {
+	err := spec.build()
+	return spec.doc, err
+}
+
+func (spec *openAPISpec) serve(w http.ResponseWriter, r *http.Request, asYAML bool) {
+	err := spec.build()
+	if err != nil {
+		spec.server.writeError(w, r, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	if asYAML {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(spec.yaml)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec.json)
+}
+
+// converts JSON to block style YAML, keeping the key order of the JSON
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	err := yaml.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+	clearYAMLStyle(&node)
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	err = enc.Encode(&node)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// JSON is read as flow style with quoted strings, the encoder quotes where needed
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
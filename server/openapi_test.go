@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type schemaAudit struct {
+	CreatedAt time.Time  `json:"createdAt" validate:"required"`
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+type schemaTree struct {
+	schemaAudit
+	Name     string            `json:"name" validate:"required,min=1"`
+	Note     string            `json:"note,omitempty"`
+	Parent   *schemaTree       `json:"parent"`
+	Children []schemaTree      `json:"children"`
+	Labels   map[string]string `json:"labels"`
+	Count    *int              `json:"count"`
+	Secret   string            `json:"-"`
+	ID       int               `json:"-" path:"id"`
+}
+
+type schemaPage[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestOpenAPIComponentSchemas(t *testing.T) {
+	schemas := newOpenAPISchemas()
+	ref := schemas.schema(reflect.TypeFor[schemaTree]())
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/schemaTree"}, ref)
+
+	tree := schemas.schemas["schemaTree"]
+	properties := tree["properties"].(map[string]any)
+	// only validate:"required" fields are required
+	assert.Equal(t, []string{"createdAt", "name"}, tree["required"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, properties["createdAt"])
+	assert.Equal(t, map[string]any{"type": []string{"string", "null"}, "format": "date-time"}, properties["deletedAt"])
+	assert.Equal(t, map[string]any{"anyOf": []any{ref, map[string]any{"type": "null"}}}, properties["parent"])
+	assert.Equal(t, map[string]any{"type": "array", "items": ref}, properties["children"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}, properties["labels"])
+	assert.Equal(t, map[string]any{"type": []string{"integer", "null"}}, properties["count"])
+	assert.NotContains(t, properties, "Secret")
+	assert.NotContains(t, properties, "schemaAudit")
+	assert.Len(t, schemas.schemas, 1)
+
+	page := schemas.schema(reflect.TypeFor[schemaPage[schemaTree]]())
+	assert.Equal(t, "#/components/schemas/schemaPage_server.schemaTree", page["$ref"])
+
+	// inline schemas don't use components
+	inline := typeToSchema(reflect.TypeFor[schemaTree]())
+	assert.Equal(t, "object", inline["type"])
+	assert.Equal(t, map[string]any{"type": []string{"object", "null"}}, inline["properties"].(map[string]any)["parent"])
+}
+
+func TestWithRequestBodyAndResponseType(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.POST("/trees", testRoute, WithRequestBody[schemaTree](), WithResponseType[schemaTree](http.StatusCreated), WithResponseType[[]schemaTree](http.StatusOK))
+
+	schemas := newOpenAPISchemas()
+	operation := createOperation(&s.Paths["/trees"][0], "1", schemas)
+	assert.Equal(t, "#/components/schemas/schemaTree", operation.RequestBody.Content["application/json"].Schema["$ref"])
+	assert.Equal(t, "Created", operation.Responses["201"].Description)
+	assert.Equal(t, "#/components/schemas/schemaTree", operation.Responses["201"].Content["application/json"].Schema["$ref"])
+	assert.Equal(t, "array", operation.Responses["200"].Content["application/json"].Schema["type"])
+	assert.Contains(t, schemas.schemas, "schemaTree")
+}
+
+type pathItemInput struct {
+	ID      int    `path:"id"`
+	Verbose bool   `query:"verbose"`
+	Name    string `json:"name"`
+}
+
+func TestOpenAPIPathItems(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GET("/users/{id}", testRoute, WithSummary("Get user"))
+	Handle(s, http.MethodPut, "/users/{id}", func(ctx context.Context, in pathItemInput) (pathItemInput, error) {
+		return in, nil
+	})
+	s.DELETE("/users/{id}", testRoute, WithOperationId("removeUser"))
+	s.GET("/files/{path...}", testRoute)
+	s.GET("/users-list", testRoute)
+	s.GET("/users/list", testRoute)
+
+	description := s.openAPIDescription()
+	item := description.Paths["/users/{id}"]
+	assert.Equal(t, []OpenAPIParam{{Name: "id", In: "path", Required: true, Schema: map[string]any{"type": "integer"}}}, item["parameters"])
+	assert.Equal(t, "getUsersById", item["get"].(OpenAPIPath).OperationId)
+	assert.Equal(t, "Get user", item["get"].(OpenAPIPath).Summary)
+	assert.Equal(t, "putUsersById", item["put"].(OpenAPIPath).OperationId)
+	assert.Equal(t, []OpenAPIParam{{Name: "verbose", In: "query", Schema: map[string]any{"type": "boolean"}}}, item["put"].(OpenAPIPath).Parameters)
+	assert.Equal(t, "removeUser", item["delete"].(OpenAPIPath).OperationId)
+
+	assert.Contains(t, description.Paths, "/files/{path}")
+	assert.Equal(t, "path", description.Paths["/files/{path}"]["parameters"].([]OpenAPIParam)[0].Name)
+	assert.Equal(t, "getUsersList", description.Paths["/users-list"]["get"].(OpenAPIPath).OperationId)
+	assert.Equal(t, "getUsersList2", description.Paths["/users/list"]["get"].(OpenAPIPath).OperationId)
+
+	first, err := json.Marshal(description)
+	assert.NoError(t, err)
+	for range 5 {
+		again, err := json.Marshal(s.openAPIDescription())
+		assert.NoError(t, err)
+		assert.Equal(t, string(first), string(again))
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	s, err := NewServer(WithOpenAPIInfo(OpenAPIInfo{
+		Title:   "Users API",
+		Version: "2.1.0",
+		License: &OpenAPILicense{Name: "MIT", Identifier: "MIT"},
+		Servers: []OpenAPIServer{{Url: "https://api.example.com", Description: "production"}},
+	}))
+	assert.NoError(t, err)
+	s.ServeOpenAPI("/openapi.json", WithOpenAPIDocs("/reference"))
+	// registered after ServeOpenAPI
+	s.GET("/users/{id}", testRoute, WithResponse(200, OpenAPIResponse{Description: "OK"}))
+	s.GET("/internal", testRoute, WithHidden())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(url string) (string, string) {
+		resp, err := http.Get(ts.URL + url)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.Header.Get("Content-Type"), string(b)
+	}
+
+	contentType, body := get("/openapi.json")
+	assert.Equal(t, "application/json", contentType)
+	var description OpenAPIDescription
+	assert.NoError(t, json.Unmarshal([]byte(body), &description))
+	assert.Equal(t, "Users API", description.Info.Title)
+	assert.Equal(t, "2.1.0", description.Info.Version)
+	assert.Equal(t, "MIT", description.Info.License.Identifier)
+	assert.Equal(t, []OpenAPIServer{{Url: "https://api.example.com", Description: "production"}}, description.Servers)
+	assert.Contains(t, description.Paths, "/users/{id}")
+	assert.NotContains(t, description.Paths, "/internal")
+	assert.NotContains(t, description.Paths, "/openapi.json")
+	assert.NotContains(t, description.Paths, "/reference")
+
+	contentType, body = get("/openapi.yaml")
+	assert.Equal(t, "application/yaml", contentType)
+	assert.Contains(t, body, "openapi: 3.1.0\n")
+	assert.Contains(t, body, "\"200\":")
+	var fromYAML map[string]any
+	assert.NoError(t, yaml.Unmarshal([]byte(body), &fromYAML))
+	assert.Equal(t, "Users API", fromYAML["info"].(map[string]any)["title"])
+
+	contentType, body = get("/reference")
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+	assert.Contains(t, body, "<title>Users API</title>")
+	assert.Contains(t, body, `var specURL = "/openapi.json";`)
+	assert.NotContains(t, body, "https://")
+}
+
+func TestWriteOpenAPI(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GET("/users", testRoute)
+	dir := t.TempDir()
+
+	assert.NoError(t, s.WriteOpenAPI(filepath.Join(dir, "openapi.yaml")))
+	data, err := os.ReadFile(filepath.Join(dir, "openapi.yaml"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "openapi: 3.1.0\n"))
+	assert.Contains(t, string(data), "operationId: getUsers")
+
+	assert.NoError(t, s.WriteOpenAPI(filepath.Join(dir, "openapi.json")))
+	info, err := os.Stat(filepath.Join(dir, "openapi.json"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0111)
+	data, err = os.ReadFile(filepath.Join(dir, "openapi.json"))
+	assert.NoError(t, err)
+	assert.True(t, json.Valid(data))
+}
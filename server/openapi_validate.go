@@ -0,0 +1,590 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/loissascha/go-logger/logger"
+)
+
+// Validate requests against the OpenAPI description of the server: path, query, header
+// and cookie params and JSON request bodies. Violations are answered with 400 and the list
+// of failing fields (see ValidationError).
+func EnableRequestValidation() ServerOption {
+	return ServerOption{
+		Name: REQUEST_VALIDATION,
+	}
+}
+
+// Check JSON responses against the documented response schemas and log violations
+// (meant for development, responses are copied while they are written)
+func EnableResponseValidation(enable bool) ServerOption {
+	v := "disable"
+	if enable {
+		v = "enable"
+	}
+	return ServerOption{
+		Name:  RESPONSE_VALIDATION,
+		Value: v,
+	}
+}
+
+// responses above this size aren't validated
+const maxValidatedResponseBytes = 1 << 20
+
+// OpenAPI description as generic JSON, as used for validation
+type openAPIDocument struct {
+	root    map[string]any
+	mu      sync.Mutex
+	regexps map[string]*regexp.Regexp
+}
+
+func newOpenAPIDocument(data []byte) (*openAPIDocument, error) {
+	doc := &openAPIDocument{regexps: map[string]*regexp.Regexp{}}
+	err := json.Unmarshal(data, &doc.root)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// operation of the path and method and its params (path item params overridden by the
+// operation's own)
+func (doc *openAPIDocument) operation(path string, method string) (map[string]any, []map[string]any) {
+	paths, _ := doc.root["paths"].(map[string]any)
+	item, _ := paths[path].(map[string]any)
+	operation, _ := item[strings.ToLower(method)].(map[string]any)
+	if operation == nil {
+		return nil, nil
+	}
+
+	params := []map[string]any{}
+	for _, raw := range append(jsonArray(item["parameters"]), jsonArray(operation["parameters"])...) {
+		param := doc.resolve(jsonObject(raw))
+		for i, existing := range params {
+			if existing["name"] == param["name"] && existing["in"] == param["in"] {
+				params = append(params[:i], params[i+1:]...)
+				break
+			}
+		}
+		params = append(params, param)
+	}
+	return operation, params
+}
+
+// follows local $refs like "#/components/schemas/User"
+func (doc *openAPIDocument) resolve(schema map[string]any) map[string]any {
+	for range 32 {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		var current any = doc.root
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			current = jsonObject(current)[token]
+		}
+		schema = jsonObject(current)
+	}
+	return schema
+}
+
+func (doc *openAPIDocument) regexp(pattern string) *regexp.Regexp {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	re, found := doc.regexps[pattern]
+	if !found {
+		// invalid patterns are cached as nil and not checked
+		re, _ = regexp.Compile(pattern)
+		doc.regexps[pattern] = re
+	}
+	return re
+}
+
+func jsonObject(v any) map[string]any {
+	object, _ := v.(map[string]any)
+	return object
+}
+
+func jsonArray(v any) []any {
+	array, _ := v.([]any)
+	return array
+}
+
+// types of a schema, "type" can be a string or a list
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := []string{}
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// JSON type of a decoded value
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return ""
+}
+
+// validate a decoded JSON value against a schema, failing fields are added to result
+func (doc *openAPIDocument) validate(schema map[string]any, value any, field string, result *ValidationError) {
+	schema = doc.resolve(schema)
+	if schema == nil {
+		return
+	}
+
+	for _, sub := range jsonArray(schema["allOf"]) {
+		doc.validate(jsonObject(sub), value, field, result)
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		alternatives := jsonArray(schema[keyword])
+		if len(alternatives) == 0 {
+			continue
+		}
+		matches := 0
+		for _, sub := range alternatives {
+			if doc.matches(jsonObject(sub), value) {
+				matches++
+			}
+		}
+		if matches == 0 || keyword == "oneOf" && matches > 1 {
+			result.add(field, "schema", "")
+			return
+		}
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		actual := jsonType(value)
+		ok := false
+		for _, t := range types {
+			ok = ok || t == actual || t == "number" && actual == "integer"
+		}
+		if !ok {
+			result.add(field, "type", strings.Join(types, " or "))
+			return
+		}
+	}
+
+	if enum := jsonArray(schema["enum"]); len(enum) > 0 {
+		ok := false
+		values := []string{}
+		for _, allowed := range enum {
+			ok = ok || reflect.DeepEqual(allowed, value)
+			values = append(values, fmt.Sprint(allowed))
+		}
+		if !ok {
+			result.add(field, "oneof", strings.Join(values, " "))
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		doc.validateString(schema, value, field, result)
+	case float64:
+		validateNumber(schema, value, field, result)
+	case []any:
+		if limit, ok := schema["minItems"].(float64); ok && float64(len(value)) < limit {
+			result.add(field, "min", formatJSONNumber(limit))
+		}
+		if limit, ok := schema["maxItems"].(float64); ok && float64(len(value)) > limit {
+			result.add(field, "max", formatJSONNumber(limit))
+		}
+		if items := jsonObject(schema["items"]); items != nil {
+			for i, item := range value {
+				doc.validate(items, item, fmt.Sprintf("%s[%d]", field, i), result)
+			}
+		}
+	case map[string]any:
+		doc.validateObject(schema, value, field, result)
+	}
+}
+
+// reports if value is valid for schema
+func (doc *openAPIDocument) matches(schema map[string]any, value any) bool {
+	result := &ValidationError{}
+	doc.validate(schema, value, "", result)
+	return len(result.Errors) == 0
+}
+
+func (doc *openAPIDocument) validateString(schema map[string]any, value string, field string, result *ValidationError) {
+	length := float64(utf8.RuneCountInString(value))
+	if limit, ok := schema["minLength"].(float64); ok && length < limit {
+		result.add(field, "min", formatJSONNumber(limit))
+	}
+	if limit, ok := schema["maxLength"].(float64); ok && length > limit {
+		result.add(field, "max", formatJSONNumber(limit))
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re := doc.regexp(pattern); re != nil && !re.MatchString(value) {
+			result.add(field, "pattern", pattern)
+		}
+	}
+
+	format, _ := schema["format"].(string)
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "date":
+		_, err = time.Parse(time.DateOnly, value)
+	case "email":
+		var address *mail.Address
+		address, err = mail.ParseAddress(value)
+		if err == nil && address.Address != value {
+			err = errors.New("invalid email")
+		}
+	case "uri":
+		var u *url.URL
+		u, err = url.ParseRequestURI(value)
+		if err == nil && u.Scheme == "" {
+			err = errors.New("relative uri")
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			err = errors.New("invalid uuid")
+		}
+	}
+	if err != nil {
+		result.add(field, "format", format)
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func validateNumber(schema map[string]any, value float64, field string, result *ValidationError) {
+	if limit, ok := schema["minimum"].(float64); ok && value < limit {
+		result.add(field, "min", formatJSONNumber(limit))
+	}
+	if limit, ok := schema["maximum"].(float64); ok && value > limit {
+		result.add(field, "max", formatJSONNumber(limit))
+	}
+	if limit, ok := schema["exclusiveMinimum"].(float64); ok && value <= limit {
+		result.add(field, "min", "> "+formatJSONNumber(limit))
+	}
+	if limit, ok := schema["exclusiveMaximum"].(float64); ok && value >= limit {
+		result.add(field, "max", "< "+formatJSONNumber(limit))
+	}
+}
+
+func (doc *openAPIDocument) validateObject(schema map[string]any, value map[string]any, field string, result *ValidationError) {
+	prefix := field + "."
+	if field == "" {
+		prefix = ""
+	}
+	for _, name := range jsonArray(schema["required"]) {
+		if name, ok := name.(string); ok {
+			if _, found := value[name]; !found {
+				result.add(prefix+name, "required", "")
+			}
+		}
+	}
+
+	properties := jsonObject(schema["properties"])
+	for _, name := range slices.Sorted(maps.Keys(value)) {
+		if property, found := properties[name]; found {
+			doc.validate(jsonObject(property), value[name], prefix+name, result)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				result.add(prefix+name, "unknown", "")
+			}
+		case map[string]any:
+			doc.validate(additional, value[name], prefix+name, result)
+		}
+	}
+}
+
+func formatJSONNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// value of a param as the type of its schema. Values that can't be converted stay strings
+// and fail the type check.
+func paramValue(schema map[string]any, raw string) any {
+	for _, t := range schemaTypes(schema) {
+		switch t {
+		case "integer", "number":
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				return n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		}
+	}
+	return raw
+}
+
+// values of a param sent with the request, nil if it is missing
+func requestParamValues(r *http.Request, param map[string]any) []string {
+	name, _ := param["name"].(string)
+	switch param["in"] {
+	case "path":
+		if value := r.PathValue(name); value != "" {
+			return []string{value}
+		}
+	case "query":
+		return r.URL.Query()[name]
+	case "header":
+		return r.Header.Values(name)
+	case "cookie":
+		if cookie, err := r.Cookie(name); err == nil {
+			return []string{cookie.Value}
+		}
+	}
+	return nil
+}
+
+// validates the params and the JSON body of a request, the body is read and replaced
+func (doc *openAPIDocument) validateRequest(r *http.Request, path string) (*ValidationError, error) {
+	operation, params := doc.operation(path, r.Method)
+	if operation == nil && r.Method == http.MethodHead {
+		operation, params = doc.operation(path, http.MethodGet)
+	}
+	if operation == nil {
+		return nil, nil
+	}
+	result := &ValidationError{Status: http.StatusBadRequest}
+
+	for _, param := range params {
+		in, _ := param["in"].(string)
+		name, _ := param["name"].(string)
+		field := in + "." + name
+		values := requestParamValues(r, param)
+		if len(values) == 0 {
+			if required, _ := param["required"].(bool); required {
+				result.add(field, "required", "")
+			}
+			continue
+		}
+		schema := doc.resolve(jsonObject(param["schema"]))
+		if slices.Contains(schemaTypes(schema), "array") {
+			items := doc.resolve(jsonObject(schema["items"]))
+			array := []any{}
+			for _, value := range values {
+				array = append(array, paramValue(items, value))
+			}
+			doc.validate(schema, array, field, result)
+			continue
+		}
+		doc.validate(schema, paramValue(schema, values[0]), field, result)
+	}
+
+	err := doc.validateRequestBody(r, jsonObject(operation["requestBody"]), result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	return nil, nil
+}
+
+func (doc *openAPIDocument) validateRequestBody(r *http.Request, requestBody map[string]any, result *ValidationError) error {
+	requestBody = doc.resolve(requestBody)
+	content := jsonObject(requestBody["content"])
+	if len(content) == 0 {
+		return nil
+	}
+	required, _ := requestBody["required"].(bool)
+	mediaType := ""
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+
+	jsonMedia := jsonObject(content["application/json"])
+	if mediaType == "" {
+		if r.ContentLength == 0 {
+			if required {
+				result.add("body", "required", "")
+			}
+			return nil
+		}
+		if jsonMedia == nil {
+			return nil
+		}
+	} else if !isJSONMediaType(mediaType) || jsonMedia == nil {
+		// other bodies are checked by their handlers (e.g. uploads)
+		if content[mediaType] == nil && content["*/*"] == nil {
+			return NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", mediaType))
+		}
+		return nil
+	}
+
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large", Err: err}
+		}
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if len(bytes.TrimSpace(data)) == 0 {
+		if required {
+			result.add("body", "required", "")
+		}
+		return nil
+	}
+	var value any
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		result.add("body", "json", "")
+		return nil
+	}
+	doc.validate(jsonObject(jsonMedia["schema"]), value, "body", result)
+	return nil
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// validates a JSON response body against the documented schema of its status
+func (doc *openAPIDocument) validateResponse(path string, method string, status int, contentType string, body []byte) *ValidationError {
+	operation, _ := doc.operation(path, method)
+	responses := jsonObject(operation["responses"])
+	response := jsonObject(responses[strconv.Itoa(status)])
+	if response == nil {
+		response = jsonObject(responses["default"])
+	}
+	response = doc.resolve(response)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media := jsonObject(jsonObject(response["content"])["application/json"])
+	if media == nil || !isJSONMediaType(mediaType) {
+		return nil
+	}
+
+	result := &ValidationError{Status: http.StatusInternalServerError}
+	var value any
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		result.add("body", "json", "")
+		return result
+	}
+	doc.validate(jsonObject(media["schema"]), value, "body", result)
+	if len(result.Errors) > 0 {
+		return result
+	}
+	return nil
+}
+
+// validates requests (and responses if enabled) of a route against the OpenAPI description
+func (s *Server) openAPIValidationMiddleware(route string) func(http.Handler) http.Handler {
+	path := openAPIRoute(route)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			doc, err := s.spec.document()
+			if err != nil {
+				logger.Error(nil, "Building the OpenAPI description for validation failed: {error}", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if s.RequestValidation {
+				validationErr, err := doc.validateRequest(r, path)
+				if err != nil {
+					s.writeHandlerError(w, r, err)
+					return
+				}
+				if validationErr != nil {
+					s.translateValidationError(r, validationErr)
+					s.writeHandlerError(w, r, validationErr)
+					return
+				}
+			}
+
+			if !s.ResponseValidation || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rw := &validatingResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+			if rw.status == 0 || rw.truncated {
+				return
+			}
+			validationErr := doc.validateResponse(path, r.Method, rw.status, rw.Header().Get("Content-Type"), rw.body.Bytes())
+			if validationErr != nil {
+				logger.Warning(nil, "Response {status} of {method} {route} doesn't match the OpenAPI description: {error}", rw.status, r.Method, route, validationErr)
+			}
+		})
+	}
+}
+
+// copies the response status and body for response validation
+type validatingResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *validatingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *validatingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.truncated && w.body.Len()+len(b) <= maxValidatedResponseBytes {
+		w.body.Write(b)
+	} else {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *validatingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
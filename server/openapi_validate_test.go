@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedItem struct {
+	ID    int      `json:"id"`
+	Email string   `json:"email,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type validatedInput struct {
+	Name  string          `json:"name" validate:"required"`
+	Items []validatedItem `json:"items"`
+	Note  *string         `json:"note"`
+}
+
+func TestRequestValidation(t *testing.T) {
+	s, err := NewServer(EnableRequestValidation())
+	assert.NoError(t, err)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	s.POST("/users/{id}", ok,
+		WithParams(
+			OpenAPIParam{Name: "id", In: "path", Schema: map[string]any{"type": "integer", "minimum": 1}},
+			OpenAPIParam{Name: "limit", In: "query", Required: true, Schema: map[string]any{"type": "integer", "maximum": 100}},
+			OpenAPIParam{Name: "sort", In: "query", Schema: map[string]any{"type": "string", "enum": []string{"asc", "desc"}}},
+			OpenAPIParam{Name: "X-Trace", In: "header", Schema: map[string]any{"type": "string", "format": "uuid"}},
+		),
+		WithRequestBody[validatedInput](),
+	)
+	s.GET("/open", ok)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	send := func(url string, contentType string, body string, header map[string]string) (int, map[string]any) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+url, strings.NewReader(body))
+		assert.NoError(t, err)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		result := map[string]any{}
+		json.Unmarshal(data, &result)
+		return resp.StatusCode, result
+	}
+	fields := func(result map[string]any) map[string]string {
+		fields := map[string]string{}
+		for _, raw := range result["errors"].([]any) {
+			fe := raw.(map[string]any)
+			fields[fe["field"].(string)] = fe["rule"].(string)
+		}
+		return fields
+	}
+
+	valid := `{"name":"a","items":[{"id":1,"tags":["x"]}],"note":null}`
+	status, _ := send("/users/1?limit=10", "application/json", valid, map[string]string{"X-Trace": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"})
+	assert.Equal(t, http.StatusOK, status)
+
+	// fields without validate:"required" can be left out
+	status, _ = send("/users/1?limit=10", "application/json", `{"name":"a","items":[{}]}`, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, result := send("/users/0?limit=abc&sort=up", "application/json", `{"items":[{"id":1.5,"tags":[1]}],"note":3}`, map[string]string{"X-Trace": "nope"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]string{
+		"path.id":               "min",
+		"query.limit":           "type",
+		"query.sort":            "oneof",
+		"header.X-Trace":        "format",
+		"body.name":             "required",
+		"body.items[0].id":      "type",
+		"body.items[0].tags[0]": "type",
+		"body.note":             "type",
+	}, fields(result))
+
+	status, result = send("/users/1", "application/json", "", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]string{"query.limit": "required", "body": "required"}, fields(result))
+
+	status, result = send("/users/1?limit=1", "application/json", "{", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]string{"body": "json"}, fields(result))
+
+	status, _ = send("/users/1?limit=1", "text/plain", "hello", nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+
+	resp, err := http.Get(ts.URL + "/open?anything=1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestResponseValidation(t *testing.T) {
+	s, err := NewServer(EnableResponseValidation(true))
+	assert.NoError(t, err)
+	s.GET("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"1"}]`))
+	}, WithResponseType[[]validatedItem](http.StatusOK))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// violations are logged, the response is passed on unchanged
+	resp, err := http.Get(ts.URL + "/items")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":"1"}]`, string(body))
+
+	doc, err := s.spec.document()
+	assert.NoError(t, err)
+	assert.Nil(t, doc.validateResponse("/items", http.MethodGet, http.StatusOK, "application/json", []byte(`[{"id":1}]`)))
+	validationErr := doc.validateResponse("/items", http.MethodGet, http.StatusOK, "application/json; charset=utf-8", []byte(`[{"id":"1","tags":"x"}]`))
+	assert.NotNil(t, validationErr)
+	assert.Len(t, validationErr.Errors, 2)
+	assert.Equal(t, "body[0].id", validationErr.Errors[0].Field)
+	assert.Equal(t, "body[0].tags must be of type array", validationErr.Errors[1].Message)
+	// undocumented status codes and content types aren't checked
+	assert.Nil(t, doc.validateResponse("/items", http.MethodGet, http.StatusNotFound, "application/json", []byte(`{}`)))
+	assert.Nil(t, doc.validateResponse("/items", http.MethodGet, http.StatusOK, "text/plain", []byte(`x`)))
+}
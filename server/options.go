@@ -0,0 +1,13 @@
+package server
+
+import "net/http"
+
+// OPTIONS route (ignores translation rules)
+func (s *Server) OPTIONSI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	s.RouteI(http.MethodOptions, route, h, opts...)
+}
+
+// OPTIONS route
+func (s *Server) OPTIONS(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	s.Route(http.MethodOptions, route, h, opts...)
+}
@@ -0,0 +1,13 @@
+package server
+
+import "net/http"
+
+// PATCH route (ignores translation rules)
+func (s *Server) PATCHI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	s.RouteI(http.MethodPatch, route, h, opts...)
+}
+
+// PATCH route
+func (s *Server) PATCH(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	s.Route(http.MethodPatch, route, h, opts...)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/loissascha/go-http-server/respond"
+)
+
+// Write an error response of the framework (404, 405, 500, CORS, ...). Uses problem details
+// if enabled, otherwise plain text.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if !s.ProblemDetails {
+		http.Error(w, message, status)
+		return
+	}
+	respond.Problem(w, r, status, respond.ProblemDetails{
+		Title:  s.problemTitle(r, status),
+		Detail: message,
+	})
+}
+
+// the translated title for the status (key problem_<status>) or the status text
+func (s *Server) problemTitle(r *http.Request, status int) string {
+	if s.TranslationsEnabled && r != nil {
+		title := s.GetTString(r, fmt.Sprintf("problem_%d", status))
+		if title != "" {
+			return title
+		}
+	}
+	return http.StatusText(status)
+}
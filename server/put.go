@@ -1,44 +1,13 @@
 package server
 
-import (
-	"fmt"
-	"net/http"
-)
+import "net/http"
 
 // PUT route (ignores translation rules)
 func (s *Server) PUTI(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
-	routeInfo := getRouteInfos(opts...)
-
-	s.addPath(route, ServerPath{
-		Route:   route,
-		Method:  METHOD_PUT,
-		Info:    routeInfo,
-		Handler: h,
-	})
+	s.RouteI(http.MethodPut, route, h, opts...)
 }
 
 // PUT route
 func (s *Server) PUT(route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
-	if !s.TranslationsEnabled {
-		s.PUTI(route, h, opts...)
-		return
-	}
-
-	routeInfo := getRouteInfos(opts...)
-	s.addPath(route, ServerPath{
-		Route:   route,
-		Method:  METHOD_PUT,
-		Info:    routeInfo,
-		Handler: s.redirectToTranslatedUrl,
-	})
-
-	for short := range s.Languages {
-		r := fmt.Sprintf("/%s%s", short, route)
-		s.addPath(r, ServerPath{
-			Route:   r,
-			Method:  METHOD_PUT,
-			Info:    routeInfo,
-			Handler: h,
-		})
-	}
+	s.Route(http.MethodPut, route, h, opts...)
 }
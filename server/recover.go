@@ -6,12 +6,12 @@ import (
 	"github.com/loissascha/go-logger/logger"
 )
 
-func recoverMiddleware(next http.Handler) http.Handler {
+func (s *Server) recoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
 				logger.Error(nil, "Recovering from an error: {error}", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				s.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
 		next.ServeHTTP(w, r)
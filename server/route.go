@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Route for any HTTP method (ignores translation rules)
+func (s *Server) RouteI(method string, route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	if !isValidMethod(method) {
+		s.addInvalidMethodError(method, route)
+		return
+	}
+
+	routeInfo := getRouteInfos(opts...)
+
+	s.addPath(route, ServerPath{
+		Route:   route,
+		Method:  Method(method),
+		Info:    routeInfo,
+		Handler: h,
+	})
+}
+
+// Route for any HTTP method, e.g. s.Route("PROPFIND", "/files/{name}", h)
+func (s *Server) Route(method string, route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption) {
+	if !s.TranslationsEnabled {
+		s.RouteI(method, route, h, opts...)
+		return
+	}
+
+	if !isValidMethod(method) {
+		s.addInvalidMethodError(method, route)
+		return
+	}
+
+	routeInfo := getRouteInfos(opts...)
+	s.addPath(route, ServerPath{
+		Route:   route,
+		Method:  Method(method),
+		Info:    routeInfo,
+		Handler: s.redirectToTranslatedUrl,
+	})
+
+	for short := range s.Languages {
+		r := fmt.Sprintf("/%s%s", short, route)
+		s.addPath(r, ServerPath{
+			Route:   r,
+			Method:  Method(method),
+			Info:    routeInfo,
+			Handler: h,
+		})
+	}
+}
+
+func (s *Server) addInvalidMethodError(method string, route string) {
+	s.registrationErrors = append(s.registrationErrors, &InvalidMethodError{
+		Registration: RouteRegistration{
+			Method:   Method(method),
+			Route:    route,
+			Location: registrationLocation(),
+		},
+	})
+}
+
+// route with wildcard names left out, "/users/{id}" and "/users/{userId}" are the same route.
+// A trailing slash matches like "{...}", so "/files/" and "/files/{path...}" are the same too.
+func routeShape(route string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(route, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(route[start:], "}")
+		if end < 0 {
+			break
+		}
+		b.WriteString(route[:start])
+		switch name := route[start+1 : start+end]; {
+		case name == "$":
+			b.WriteString("{$}")
+		case strings.HasSuffix(name, "..."):
+			b.WriteString("{...}")
+		default:
+			b.WriteString("{}")
+		}
+		route = route[start+end+1:]
+	}
+	b.WriteString(route)
+	if strings.HasSuffix(route, "/") {
+		b.WriteString("{...}")
+	}
+	return b.String()
+}
+
+// Routes only differing in wildcard names and routes matching the requests of a mount
+// prefix would make http.ServeMux panic. p is a route that isn't registered yet.
+func (s *Server) patternConflict(p ServerPath) error {
+	shape := routeShape(p.Route)
+	for route, paths := range s.Paths {
+		if routeShape(route) == shape {
+			first := paths[0]
+			if i := slices.IndexFunc(paths, func(existing ServerPath) bool { return !existing.mock }); i >= 0 {
+				first = paths[i]
+			}
+			return &RouteConflictError{First: first.Registration, Second: p.Registration}
+		}
+	}
+	for _, m := range s.mounts {
+		if routeShape(m.prefix+"/") == shape {
+			return &RouteConflictError{First: m.registration, Second: p.Registration}
+		}
+	}
+	return nil
+}
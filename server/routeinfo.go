@@ -9,20 +9,37 @@ import (
 type RouteInfo struct {
 	Summary     string
 	Description string
+	OperationId string
 	Tags        []string
 	Middlewares []func(http.Handler) http.Handler
 	Params      []OpenAPIParam
 	Responses   map[string]OpenAPIResponse
 	ExportTypes []reflect.Type
+	CORS        *CORSConfig
+	WebSocket   *WSConfig
+	Upload      *UploadConfig
+	Tus         *TusConfig
+	// nil uses the server default (see WithMaxBodyBytes)
+	MaxBodyBytes *int64
+	StrictJSON   bool
+	// left out of the OpenAPI description
+	Hidden bool
+	// alternative security requirements, each maps the schemes it needs together to their
+	// scopes (see WithSecurity and WithSecurityRequirement)
+	Security []map[string][]string
+	// documented request body and response types (set by typed handlers)
+	RequestType   reflect.Type
+	ResponseTypes map[string]reflect.Type
 }
 
 func newRouteInfo() RouteInfo {
 	routeInfo := RouteInfo{
-		Middlewares: []func(http.Handler) http.Handler{},
-		Tags:        []string{},
-		Params:      []OpenAPIParam{},
-		Responses:   map[string]OpenAPIResponse{},
-		ExportTypes: []reflect.Type{},
+		Middlewares:   []func(http.Handler) http.Handler{},
+		Tags:          []string{},
+		Params:        []OpenAPIParam{},
+		Responses:     map[string]OpenAPIResponse{},
+		ExportTypes:   []reflect.Type{},
+		ResponseTypes: map[string]reflect.Type{},
 	}
 	return routeInfo
 }
@@ -41,6 +58,20 @@ func WithSummary(summary string) RouteOption {
 	}
 }
 
+// OpenAPI operationId of the route (derived from method and route by default)
+func WithOperationId(operationId string) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.OperationId = operationId
+	}
+}
+
+// Leave the route out of the OpenAPI description
+func WithHidden() RouteOption {
+	return func(ri *RouteInfo) {
+		ri.Hidden = true
+	}
+}
+
 func WithDescription(description string) RouteOption {
 	return func(ri *RouteInfo) {
 		ri.Description = description
@@ -65,15 +96,39 @@ func WithMiddlewares(mws ...func(http.Handler) http.Handler) RouteOption {
 	}
 }
 
+// Use a different CORS policy for this route than the one of the server
+func WithRouteCORS(config CORSConfig) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.CORS = &config
+	}
+}
+
 func WithExportType[T any]() RouteOption {
 	return func(ri *RouteInfo) {
 		t := reflect.TypeOf((*T)(nil)).Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
 		if t.Kind() == reflect.Struct {
 			ri.ExportTypes = append(ri.ExportTypes, t)
 		}
 	}
 }
 
+// Document T as JSON request body of the route
+func WithRequestBody[T any]() RouteOption {
+	return func(ri *RouteInfo) {
+		ri.RequestType = reflect.TypeFor[T]()
+	}
+}
+
+// Document T as JSON response content for the status code
+func WithResponseType[T any](responseCode int) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.ResponseTypes[fmt.Sprintf("%v", responseCode)] = reflect.TypeFor[T]()
+	}
+}
+
 func getRouteInfos(opts ...RouteOption) RouteInfo {
 	routeInfo := newRouteInfo()
 	for _, opt := range opts {
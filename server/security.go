@@ -0,0 +1,239 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// OpenAPI security scheme (see WithSecurityScheme). Routes declaring the scheme with
+// WithSecurity are only enforced if Authenticate is set, otherwise they are just documented.
+type SecurityScheme struct {
+	// http, apiKey, oauth2 or openIdConnect
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// bearer or basic for the http type
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	// header, query or cookie name and location of an apiKey
+	Name             string      `json:"name,omitempty"`
+	In               string      `json:"in,omitempty"`
+	Flows            *OAuthFlows `json:"flows,omitempty"`
+	OpenIdConnectUrl string      `json:"openIdConnectUrl,omitempty"`
+	// Checks the credential of a request: the bearer token (http bearer, oauth2 and
+	// openIdConnect), the key (apiKey) or "user:password" (http basic). scopes are the
+	// scopes the route requires. The returned request (e.g. with the user in its context) is
+	// passed on if not nil. Errors with a status (HTTPError) are written as is, others as 401.
+	Authenticate func(r *http.Request, credential string, scopes []string) (*http.Request, error) `json:"-"`
+}
+
+type OAuthFlows struct {
+	Implicit          *OAuthFlow `json:"implicit,omitempty"`
+	Password          *OAuthFlow `json:"password,omitempty"`
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+type OAuthFlow struct {
+	AuthorizationUrl string            `json:"authorizationUrl,omitempty"`
+	TokenUrl         string            `json:"tokenUrl,omitempty"`
+	RefreshUrl       string            `json:"refreshUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+// http bearer scheme, format is a hint like "JWT"
+func BearerSecurity(format string) SecurityScheme {
+	return SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: format}
+}
+
+// http basic scheme
+func BasicSecurity() SecurityScheme {
+	return SecurityScheme{Type: "http", Scheme: "basic"}
+}
+
+// API key in a header, query parameter or cookie (in is "header", "query" or "cookie")
+func APIKeySecurity(in string, name string) SecurityScheme {
+	return SecurityScheme{Type: "apiKey", In: in, Name: name}
+}
+
+// OAuth2 scheme, requests carry the access token as bearer token
+func OAuth2Security(flows OAuthFlows) SecurityScheme {
+	return SecurityScheme{Type: "oauth2", Flows: &flows}
+}
+
+// Require the security scheme name (see WithSecurityScheme) with the given scopes.
+// Using the option more than once allows any of the schemes. The requirements of a group
+// and of its routes both have to be met.
+func WithSecurity(name string, scopes ...string) RouteOption {
+	return WithSecurityRequirement(map[string][]string{name: scopes})
+}
+
+// Require all schemes of requirement (scheme name to scopes) together, e.g. a bearer token
+// and an API key. Like WithSecurity it adds an alternative to the other requirements of
+// the route, an empty requirement allows anonymous access.
+func WithSecurityRequirement(requirement map[string][]string) RouteOption {
+	return func(ri *RouteInfo) {
+		combined := map[string][]string{}
+		for name, scopes := range requirement {
+			combined[name] = append([]string{}, scopes...)
+		}
+		ri.Security = append(ri.Security, combined)
+	}
+}
+
+// requirements of a group and of a route (or nested group) that both have to be met: every
+// pair of their alternatives becomes one requirement
+func combineSecurity(parent []map[string][]string, child []map[string][]string) []map[string][]string {
+	if len(parent) == 0 {
+		return slices.Clone(child)
+	}
+	if len(child) == 0 {
+		return slices.Clone(parent)
+	}
+	result := []map[string][]string{}
+	for _, p := range parent {
+		for _, c := range child {
+			combined := map[string][]string{}
+			for name, scopes := range p {
+				combined[name] = append([]string{}, scopes...)
+			}
+			for name, scopes := range c {
+				if combined[name] == nil {
+					combined[name] = []string{}
+				}
+				for _, scope := range scopes {
+					if !slices.Contains(combined[name], scope) {
+						combined[name] = append(combined[name], scope)
+					}
+				}
+			}
+			result = append(result, combined)
+		}
+	}
+	return result
+}
+
+var errMissingCredential = errors.New("missing credentials")
+
+// credential of the scheme sent with the request, "" if there is none
+func (scheme SecurityScheme) credential(r *http.Request) string {
+	switch scheme.Type {
+	case "apiKey":
+		switch scheme.In {
+		case "query":
+			return r.URL.Query().Get(scheme.Name)
+		case "cookie":
+			cookie, err := r.Cookie(scheme.Name)
+			if err != nil {
+				return ""
+			}
+			return cookie.Value
+		default:
+			return r.Header.Get(scheme.Name)
+		}
+	case "http":
+		if strings.EqualFold(scheme.Scheme, "basic") {
+			user, password, ok := r.BasicAuth()
+			if !ok {
+				return ""
+			}
+			return user + ":" + password
+		}
+	}
+	authScheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(authScheme, "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// challenge for the WWW-Authenticate header of 401 responses
+func (scheme SecurityScheme) challenge() string {
+	switch {
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+		return `Basic realm="restricted", charset="UTF-8"`
+	case scheme.Type == "apiKey":
+		return ""
+	default:
+		return "Bearer"
+	}
+}
+
+// enforces the security requirements of a route: the request passes if any requirement
+// is met. Requirements of schemes without Authenticate always pass.
+func (s *Server) securityMiddleware(requirements []map[string][]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var firstErr error
+			challenges := []string{}
+			for _, requirement := range requirements {
+				authenticated, err := s.authenticate(r, requirement)
+				if err == nil {
+					next.ServeHTTP(w, authenticated)
+					return
+				}
+				if firstErr == nil || errors.Is(firstErr, errMissingCredential) && !errors.Is(err, errMissingCredential) {
+					firstErr = err
+				}
+				for name := range requirement {
+					challenge := s.SecuritySchemes[name].challenge()
+					if challenge != "" && !slices.Contains(challenges, challenge) {
+						challenges = append(challenges, challenge)
+					}
+				}
+			}
+
+			var sc StatusCoder
+			if errors.As(firstErr, &sc) {
+				if sc.StatusCode() == http.StatusUnauthorized {
+					setChallenges(w, challenges)
+				}
+				s.writeHandlerError(w, r, firstErr)
+				return
+			}
+			setChallenges(w, challenges)
+			s.writeError(w, r, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		})
+	}
+}
+
+// checks all schemes of a requirement, each returned request is passed to the next scheme
+func (s *Server) authenticate(r *http.Request, requirement map[string][]string) (*http.Request, error) {
+	for name, scopes := range requirement {
+		scheme := s.SecuritySchemes[name]
+		if scheme.Authenticate == nil {
+			continue
+		}
+		credential := scheme.credential(r)
+		if credential == "" {
+			return nil, errMissingCredential
+		}
+		authenticated, err := scheme.Authenticate(r, credential, scopes)
+		if err != nil {
+			return nil, err
+		}
+		if authenticated != nil {
+			r = authenticated
+		}
+	}
+	return r, nil
+}
+
+func setChallenges(w http.ResponseWriter, challenges []string) {
+	for _, challenge := range challenges {
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+}
+
+// routes requiring schemes that aren't registered with WithSecurityScheme
+func (s *Server) checkSecuritySchemes(p ServerPath) error {
+	for _, requirement := range p.Info.Security {
+		for name := range requirement {
+			if _, found := s.SecuritySchemes[name]; !found {
+				return &UnknownSecuritySchemeError{Scheme: name, Registration: p.Registration}
+			}
+		}
+	}
+	return nil
+}
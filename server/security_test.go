@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type securityUserKey struct{}
+
+func TestSecurity(t *testing.T) {
+	bearer := BearerSecurity("JWT")
+	bearer.Authenticate = func(r *http.Request, token string, scopes []string) (*http.Request, error) {
+		if token != "admin-token" && token != "user-token" {
+			return nil, errors.New("invalid token")
+		}
+		if slices.Contains(scopes, "admin") && token != "admin-token" {
+			return nil, NewHTTPError(http.StatusForbidden, "missing scope admin")
+		}
+		return r.WithContext(context.WithValue(r.Context(), securityUserKey{}, token)), nil
+	}
+	apiKey := APIKeySecurity("header", "X-API-Key")
+	apiKey.Authenticate = func(r *http.Request, key string, scopes []string) (*http.Request, error) {
+		if key != "secret" {
+			return nil, errors.New("invalid key")
+		}
+		return nil, nil
+	}
+
+	s, err := NewServer(WithSecurityScheme("bearer", bearer), WithSecurityScheme("apiKey", apiKey), WithSecurityScheme("docsOnly", BasicSecurity()))
+	assert.NoError(t, err)
+	user := func(w http.ResponseWriter, r *http.Request) {
+		token, _ := r.Context().Value(securityUserKey{}).(string)
+		w.Write([]byte(token))
+	}
+	s.GET("/me", user, WithSecurity("bearer"), WithSecurity("apiKey"))
+	admin := s.Group("/admin", WithSecurity("bearer", "admin"))
+	admin.GET("/stats", user)
+	s.GET("/documented", user, WithSecurity("docsOnly"))
+	assert.NoError(t, s.Validate())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(url string, header map[string]string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+url, nil)
+		assert.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/me", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, []string{"Bearer"}, resp.Header.Values("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, get("/me", map[string]string{"Authorization": "Bearer wrong"}).StatusCode)
+	assert.Equal(t, http.StatusOK, get("/me", map[string]string{"Authorization": "Bearer user-token"}).StatusCode)
+	assert.Equal(t, http.StatusOK, get("/me", map[string]string{"X-API-Key": "secret"}).StatusCode)
+
+	assert.Equal(t, http.StatusForbidden, get("/admin/stats", map[string]string{"Authorization": "Bearer user-token"}).StatusCode)
+	assert.Equal(t, http.StatusOK, get("/admin/stats", map[string]string{"Authorization": "Bearer admin-token"}).StatusCode)
+	assert.Equal(t, http.StatusOK, get("/documented", nil).StatusCode)
+
+	description := s.openAPIDescription()
+	schemes := description.Components["securitySchemes"].(map[string]SecurityScheme)
+	assert.Equal(t, "bearer", schemes["bearer"].Scheme)
+	assert.Equal(t, "X-API-Key", schemes["apiKey"].Name)
+	me := description.Paths["/me"]["get"].(OpenAPIPath)
+	assert.Equal(t, []map[string]any{{"bearer": []string{}}, {"apiKey": []string{}}}, me.Security)
+	assert.Equal(t, "Unauthorized", me.Responses["401"].Description)
+	stats := description.Paths["/admin/stats"]["get"].(OpenAPIPath)
+	assert.Equal(t, []map[string]any{{"bearer": []string{"admin"}}}, stats.Security)
+}
+
+func TestGroupAndRouteSecurity(t *testing.T) {
+	tokenScheme := func(valid string) SecurityScheme {
+		scheme := APIKeySecurity("header", "X-"+valid)
+		scheme.Authenticate = func(r *http.Request, key string, scopes []string) (*http.Request, error) {
+			if key != valid {
+				return nil, errors.New("invalid key")
+			}
+			return nil, nil
+		}
+		return scheme
+	}
+	s, err := NewServer(WithSecurityScheme("admin", tokenScheme("admin")), WithSecurityScheme("user", tokenScheme("user")))
+	assert.NoError(t, err)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	admin := s.Group("/admin", WithSecurity("admin"))
+	admin.GET("/stats", ok, WithSecurity("user"))
+	s.GET("/both", ok, WithSecurityRequirement(map[string][]string{"admin": nil, "user": nil}))
+	s.GET("/optional", ok, WithSecurity("user"), WithSecurityRequirement(map[string][]string{}))
+	assert.NoError(t, s.Validate())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(url string, header map[string]string) int {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+url, nil)
+		assert.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	both := map[string]string{"X-admin": "admin", "X-user": "user"}
+
+	// the group requirement and the route requirement both have to pass
+	assert.Equal(t, http.StatusUnauthorized, get("/admin/stats", map[string]string{"X-user": "user"}))
+	assert.Equal(t, http.StatusUnauthorized, get("/admin/stats", map[string]string{"X-admin": "admin"}))
+	assert.Equal(t, http.StatusOK, get("/admin/stats", both))
+
+	assert.Equal(t, http.StatusUnauthorized, get("/both", map[string]string{"X-user": "user"}))
+	assert.Equal(t, http.StatusOK, get("/both", both))
+
+	assert.Equal(t, http.StatusOK, get("/optional", nil))
+
+	description := s.openAPIDescription()
+	stats := description.Paths["/admin/stats"]["get"].(OpenAPIPath)
+	assert.Equal(t, []map[string]any{{"admin": []string{}, "user": []string{}}}, stats.Security)
+	optional := description.Paths["/optional"]["get"].(OpenAPIPath)
+	assert.Equal(t, []map[string]any{{"user": []string{}}, {}}, optional.Security)
+}
+
+func TestCombineSecurity(t *testing.T) {
+	parent := []map[string][]string{{"bearer": {"admin"}}, {"apiKey": {}}}
+	child := []map[string][]string{{"bearer": {"reports"}}}
+	assert.Equal(t, []map[string][]string{
+		{"bearer": {"admin", "reports"}},
+		{"apiKey": {}, "bearer": {"reports"}},
+	}, combineSecurity(parent, child))
+	assert.Equal(t, child, combineSecurity(nil, child))
+}
+
+func TestUnknownSecurityScheme(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GET("/me", testRoute, WithSecurity("bearer"))
+
+	var schemeErr *UnknownSecuritySchemeError
+	assert.ErrorAs(t, s.Validate(), &schemeErr)
+	assert.Equal(t, "bearer", schemeErr.Scheme)
+}
@@ -4,56 +4,119 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/loissascha/go-logger/logger"
 )
 
-type Method int
+// HTTP method of a route. Any RFC 9110 method token can be used, the constants
+// cover the common ones.
+type Method string
 
 const (
-	METHOD_GET    Method = 0
-	METHOD_POST   Method = 1
-	METHOD_PUT    Method = 2
-	METHOD_DELETE Method = 3
+	METHOD_GET     Method = http.MethodGet
+	METHOD_HEAD    Method = http.MethodHead
+	METHOD_POST    Method = http.MethodPost
+	METHOD_PUT     Method = http.MethodPut
+	METHOD_DELETE  Method = http.MethodDelete
+	METHOD_PATCH   Method = http.MethodPatch
+	METHOD_OPTIONS Method = http.MethodOptions
 )
 
 type ServerPath struct {
-	Route   string
-	Method  Method
-	Info    RouteInfo
-	Handler func(w http.ResponseWriter, r *http.Request)
+	Route        string
+	Method       Method
+	Info         RouteInfo
+	Handler      func(w http.ResponseWriter, r *http.Request)
+	Registration RouteRegistration
+	// registered by MockOpenAPI, replaced by real handlers
+	mock bool
 }
 
 type Server struct {
 	mu                        sync.Mutex
 	mux                       *http.ServeMux
 	Paths                     map[string][]ServerPath
+	mounts                    []mountedHandler
+	registrationErrors        []error
+	middlewares               []func(http.Handler) http.Handler
+	beforeMiddlewares         []func(http.Handler) http.Handler
+	afterMiddlewares          []func(http.Handler) http.Handler
 	Options                   []ServerOption
 	httpServer                *http.Server
-	handlersSetup             bool
+	handlersSetup             atomic.Bool
 	ReadHeaderTimeout         time.Duration
 	ReadTimeout               time.Duration
 	WriteTimeout              time.Duration
 	IdleTimeout               time.Duration
 	MaxHeaderBytes            int
+	MaxBodyBytes              int64
 	TranslationsEnabled       bool
 	AutoDetectLanguageEnabled bool
 	Languages                 map[string]map[string]string
 	DefaultLanguage           string
 	ExportTypes               bool
 	ExportTypesLocation       string
+	ProblemDetails            bool
+	CORS                      *CORSConfig
+	OpenAPIInfo               OpenAPIInfo
+	SecuritySchemes           map[string]SecurityScheme
+	RequestValidation         bool
+	ResponseValidation        bool
+	spec                      *openAPISpec
+	corsPolicy                *corsPolicy
+	streamsCtx                context.Context
+	// server this one is mounted in, its shutdown closes the streams of this server too
+	parent  *Server
+	wsMu    sync.Mutex
+	wsConns map[*WSConn]struct{}
 }
 
 func (s *Server) addPath(route string, p ServerPath) {
+	p.Registration = RouteRegistration{
+		Method:   p.Method,
+		Route:    p.Route,
+		Location: registrationLocation(),
+	}
+	err := s.checkSecuritySchemes(p)
+	if err != nil {
+		s.registrationErrors = append(s.registrationErrors, err)
+	}
+	p, ok := s.replaceMock(p)
+	if !ok {
+		return
+	}
+	route = p.Route
 	sp, found := s.Paths[route]
 	if !found {
+		err := s.patternConflict(p)
+		if err != nil {
+			// mocks only fill the gaps
+			if !p.mock {
+				s.registrationErrors = append(s.registrationErrors, err)
+			}
+			return
+		}
 		s.Paths[route] = []ServerPath{
 			p,
 		}
 		return
 	}
+	for _, existing := range sp {
+		if existing.Method == p.Method {
+			s.registrationErrors = append(s.registrationErrors, &RouteConflictError{
+				First:  existing.Registration,
+				Second: p.Registration,
+			})
+			return
+		}
+	}
 	s.Paths[route] = append(sp, p)
 }
 
@@ -66,14 +129,25 @@ func NewServer(options ...ServerOption) (*Server, error) {
 		WriteTimeout:        15 * time.Second,
 		IdleTimeout:         60 * time.Second,
 		MaxHeaderBytes:      1 << 20,
+		MaxBodyBytes:        DEFAULT_MAX_BODY_BYTES,
 		Languages:           map[string]map[string]string{},
+		SecuritySchemes:     map[string]SecurityScheme{},
 		ExportTypesLocation: "./export.ts",
 	}
 	err := s.initServerOptions()
 	if err != nil {
 		return nil, err
 	}
+	if s.CORS == nil {
+		config, err := CORSConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		s.CORS = &config
+	}
+	s.corsPolicy = newCORSPolicy(*s.CORS)
 	s.mux = http.NewServeMux()
+	s.spec = &openAPISpec{server: &s}
 	return &s, nil
 }
 
@@ -167,156 +241,226 @@ func (s *Server) GetMux() *http.ServeMux {
 type chainHandler struct {
 	chain   []func(http.Handler) http.Handler
 	handler *func(http.ResponseWriter, *http.Request)
+	cors    *corsPolicy
 }
 
-type routeMethodHandlers struct {
-	get    chainHandler
-	post   chainHandler
-	put    chainHandler
-	delete chainHandler
-}
+type routeMethodHandlers map[Method]chainHandler
 
 // create chain and handler for every method in this server paths array (per route)
 func (s *Server) getRouteServerPathsChainAndHandler(serverPaths []ServerPath) routeMethodHandlers {
 	result := routeMethodHandlers{}
-	getChain := []func(http.Handler) http.Handler{}
-	var getHandler *func(http.ResponseWriter, *http.Request)
-	postChain := []func(http.Handler) http.Handler{}
-	var postHandler *func(http.ResponseWriter, *http.Request)
-	putChain := []func(http.Handler) http.Handler{}
-	var putHandler *func(http.ResponseWriter, *http.Request)
-	deleteChain := []func(http.Handler) http.Handler{}
-	var deleteHandler *func(http.ResponseWriter, *http.Request)
 	for _, path := range serverPaths {
-		switch path.Method {
-		case METHOD_GET:
-			if getHandler != nil {
-				panic("double GET route!")
-			}
-			allMiddlewares := append(path.Info.Middlewares, getRequest)
-			getChain = allMiddlewares
-			getHandler = &path.Handler
-		case METHOD_POST:
-			if postHandler != nil {
-				panic("double POST route!")
-			}
-			allMiddlewares := append(path.Info.Middlewares, postRequest)
-			postChain = allMiddlewares
-			postHandler = &path.Handler
-		case METHOD_PUT:
-			if putHandler != nil {
-				panic("double PUT route!")
-			}
-			allMiddlewares := append(path.Info.Middlewares, putRequest)
-			putChain = allMiddlewares
-			putHandler = &path.Handler
-		case METHOD_DELETE:
-			if deleteHandler != nil {
-				panic("double DELETE route!")
-			}
-			allMiddlewares := append(path.Info.Middlewares, deleteRequest)
-			deleteChain = allMiddlewares
-			deleteHandler = &path.Handler
-		default:
-			panic("method not implemented .")
+		allMiddlewares := []func(http.Handler) http.Handler{s.bodyMiddleware(s.routeMaxBodyBytes(path.Info), path.Info.StrictJSON)}
+		if len(path.Info.Security) > 0 {
+			allMiddlewares = append(allMiddlewares, s.securityMiddleware(path.Info.Security))
+		}
+		if (s.RequestValidation || s.ResponseValidation) && !path.Info.Hidden {
+			allMiddlewares = append(allMiddlewares, s.openAPIValidationMiddleware(path.Route))
+		}
+		allMiddlewares = append(allMiddlewares, path.Info.Middlewares...)
+		allMiddlewares = append(allMiddlewares, methodRequest(path.Method))
+		if path.Info.Upload != nil {
+			allMiddlewares = append(allMiddlewares, s.uploadMiddleware(*path.Info.Upload))
+		}
+		var cors *corsPolicy
+		if path.Info.CORS != nil {
+			cors = newCORSPolicy(*path.Info.CORS)
+		}
+		result[path.Method] = chainHandler{
+			chain:   allMiddlewares,
+			handler: &path.Handler,
+			cors:    cors,
 		}
 	}
-	result.get = chainHandler{
-		chain:   getChain,
-		handler: getHandler,
-	}
-	result.post = chainHandler{
-		chain:   postChain,
-		handler: postHandler,
-	}
-	result.put = chainHandler{
-		chain:   putChain,
-		handler: putHandler,
+	return result
+}
+
+// value for the Allow header: all registered methods, HEAD for GET routes and OPTIONS
+func (m routeMethodHandlers) allowHeader() string {
+	methods := []string{string(METHOD_OPTIONS)}
+	for method := range m {
+		if !slices.Contains(methods, string(method)) {
+			methods = append(methods, string(method))
+		}
 	}
-	result.delete = chainHandler{
-		chain:   deleteChain,
-		handler: deleteHandler,
+	if _, found := m[METHOD_GET]; found && !slices.Contains(methods, string(METHOD_HEAD)) {
+		methods = append(methods, string(METHOD_HEAD))
 	}
-	return result
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
 }
 
 func (s *Server) setupHandlers() error {
 	for route, serverPaths := range s.Paths {
 		data := s.getRouteServerPathsChainAndHandler(serverPaths)
-		s.mux.Handle(route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.Method == http.MethodOptions {
-				finalHandler := chainMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		allow := data.allowHeader()
+		err := handlePattern(s.mux, route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// CORS preflights are always answered by the cors middleware, other OPTIONS
+			// requests go to the user defined OPTIONS handler or get the allowed methods
+			_, hasOptions := data[METHOD_OPTIONS]
+			if r.Method == http.MethodOptions && (!hasOptions || isPreflightRequest(r)) {
+				finalHandler := s.chainWithCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Allow", allow)
+					w.WriteHeader(http.StatusNoContent)
+				}), data.preflightPolicy(r))
 				finalHandler.ServeHTTP(w, r)
 				return
 			}
 
-			var chain []func(http.Handler) http.Handler
-			var h *func(http.ResponseWriter, *http.Request)
-			switch r.Method {
-			case http.MethodGet:
-				chain = data.get.chain
-				h = data.get.handler
-			case http.MethodPost:
-				chain = data.post.chain
-				h = data.post.handler
-			case http.MethodPut:
-				chain = data.put.chain
-				h = data.put.handler
-			case http.MethodDelete:
-				chain = data.delete.chain
-				h = data.delete.handler
-			default:
-				http.Error(w, fmt.Sprintf("Method %s Not Allowed", r.Method), http.StatusMethodNotAllowed)
-				return
+			ch, found := data[Method(r.Method)]
+			if !found && r.Method == http.MethodHead {
+				// HEAD is answered by the GET handler, net/http discards the body
+				ch, found = data[METHOD_GET]
 			}
-
-			if h == nil {
-				http.Error(w, "Not found", http.StatusNotFound)
+			if !found {
+				finalHandler := s.chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Allow", allow)
+					s.writeError(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s Not Allowed", r.Method))
+				}))
+				finalHandler.ServeHTTP(w, r)
 				return
 			}
 
-			finalHandler := chainMiddleware(http.HandlerFunc(*h), chain...)
+			finalHandler := s.chainWithCORS(http.HandlerFunc(*ch.handler), ch.cors, ch.chain...)
 			finalHandler.ServeHTTP(w, r)
 		}))
+		if err != nil {
+			return err
+		}
 	}
+	return s.setupMounts()
+}
+
+// registers a pattern, conflicts Validate didn't catch are returned instead of panicking
+func handlePattern(mux *http.ServeMux, pattern string, h http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("register %s: %v", pattern, r)
+		}
+	}()
+	mux.Handle(pattern, h)
 	return nil
 }
 
+// sets up the handlers if that didn't happen yet
+func (s *Server) setupHandlersLocked() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setupHandlersOnce()
+}
+
+// sets up the handlers if that didn't happen yet (s.mu has to be locked)
+func (s *Server) setupHandlersOnce() error {
+	if s.handlersSetup.Load() {
+		return nil
+	}
+	err := s.Validate()
+	if err != nil {
+		return err
+	}
+	err = s.setupHandlers()
+	if err != nil {
+		return err
+	}
+	s.handlersSetup.Store(true)
+	return nil
+}
+
+// Serve a single request. This makes the server usable as http.Handler, e.g. for Mount or httptest.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// only the first requests wait for the lock, afterwards the handlers are read only
+	if !s.handlersSetup.Load() {
+		err := s.setupHandlersLocked()
+		if err != nil {
+			logger.Error(nil, "Setting up handlers failed: {error}", err)
+			s.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+	}
+
+	// requests no route matches still get the global middlewares. Without a pattern the mux
+	// answers with a 404, a 405 (method patterns of Handle) or a redirect, only its 404 is
+	// replaced.
+	if h, pattern := s.mux.Handler(r); pattern == "" {
+		s.chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mw := &muxNotFoundWriter{w: w, header: http.Header{}}
+			h.ServeHTTP(mw, r)
+			if mw.notFound {
+				s.writeError(w, r, http.StatusNotFound, "404 page not found")
+			}
+		})).ServeHTTP(w, r)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// passes the response of a mux handler on unless it is a 404
+type muxNotFoundWriter struct {
+	w        http.ResponseWriter
+	header   http.Header
+	wrote    bool
+	notFound bool
+}
+
+func (mw *muxNotFoundWriter) Header() http.Header {
+	return mw.header
+}
+
+func (mw *muxNotFoundWriter) WriteHeader(status int) {
+	if mw.wrote {
+		return
+	}
+	mw.wrote = true
+	if status == http.StatusNotFound {
+		mw.notFound = true
+		return
+	}
+	maps.Copy(mw.w.Header(), mw.header)
+	mw.w.WriteHeader(status)
+}
+
+func (mw *muxNotFoundWriter) Write(b []byte) (int, error) {
+	if !mw.wrote {
+		mw.WriteHeader(http.StatusOK)
+	}
+	if mw.notFound {
+		return len(b), nil
+	}
+	return mw.w.Write(b)
+}
+
 func (s *Server) prepareHTTPServer(addr string) (*http.Server, error) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.httpServer != nil {
-		s.mu.Unlock()
 		return nil, fmt.Errorf("server already running")
 	}
 
-	if !s.handlersSetup {
-		err := s.setupHandlers()
-		if err != nil {
-			s.mu.Unlock()
-			return nil, err
-		}
-		s.handlersSetup = true
+	err := s.setupHandlersOnce()
+	if err != nil {
+		return nil, err
 	}
 
 	if s.ExportTypes {
 		err := s.exportInterfacesToTS()
 		if err != nil {
-			s.mu.Unlock()
 			return nil, err
 		}
 	}
 
 	httpServer := &http.Server{
 		Addr:              addr,
-		Handler:           s.mux,
+		Handler:           s,
 		ReadHeaderTimeout: s.ReadHeaderTimeout,
 		ReadTimeout:       s.ReadTimeout,
 		WriteTimeout:      s.WriteTimeout,
 		IdleTimeout:       s.IdleTimeout,
 		MaxHeaderBytes:    s.MaxHeaderBytes,
 	}
+	streamsCtx, cancelStreams := context.WithCancel(context.Background())
+	httpServer.RegisterOnShutdown(cancelStreams)
+	s.streamsCtx = streamsCtx
 	s.httpServer = httpServer
-	s.mu.Unlock()
 
 	return httpServer, nil
 }
@@ -366,6 +510,8 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return nil
 	}
 
+	// hijacked connections aren't handled by http.Server
+	s.closeWSConns(WS_CLOSE_GOING_AWAY, "server shutting down")
 	err := httpServer.Shutdown(ctx)
 
 	s.mu.Lock()
@@ -386,6 +532,7 @@ func (s *Server) Close() error {
 		return nil
 	}
 
+	s.closeWSConns(WS_CLOSE_GOING_AWAY, "server closed")
 	err := httpServer.Close()
 
 	s.mu.Lock()
@@ -402,5 +549,7 @@ func (s *Server) Close() error {
 }
 
 func (s *Server) Handle(route string, h func(w http.ResponseWriter, r *http.Request), middlewares ...func(http.Handler) http.Handler) {
-	s.mux.Handle(route, chainMiddleware(http.HandlerFunc(h), middlewares...))
+	s.mux.Handle(route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.chain(http.HandlerFunc(h), middlewares...).ServeHTTP(w, r)
+	}))
 }
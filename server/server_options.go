@@ -23,12 +23,20 @@ const (
 	WRITE_TIMEOUT                 ServerOptionName = "write_timeout"
 	IDLE_TIMEOUT                  ServerOptionName = "idle_timeout"
 	MAX_HEADER_BYTES              ServerOptionName = "max_header_bytes"
+	MAX_BODY_BYTES                ServerOptionName = "max_body_bytes"
+	CORS                          ServerOptionName = "cors"
+	PROBLEM_DETAILS               ServerOptionName = "problem_details"
+	OPENAPI_INFO                  ServerOptionName = "openapi_info"
+	SECURITY_SCHEME               ServerOptionName = "security_scheme"
+	REQUEST_VALIDATION            ServerOptionName = "request_validation"
+	RESPONSE_VALIDATION           ServerOptionName = "response_validation"
 )
 
 type ServerOption struct {
 	Name     ServerOptionName
 	Value    string
 	Filename string
+	Data     any
 }
 
 func EnableTranslations() ServerOption {
@@ -111,13 +119,57 @@ func SetMaxHeaderBytes(n int) ServerOption {
 	}
 }
 
+// Default request body limit of routes, 10 MiB if not set (n <= 0 disables the limit)
+func SetMaxBodyBytes(n int64) ServerOption {
+	return ServerOption{
+		Name:  MAX_BODY_BYTES,
+		Value: strconv.FormatInt(n, 10),
+	}
+}
+
+// Configure the CORS policy of the server (instead of ALLOWED_ORIGINS and APP_ENV)
+func WithCORS(config CORSConfig) ServerOption {
+	return ServerOption{
+		Name: CORS,
+		Data: config,
+	}
+}
+
+// Use RFC 9457 application/problem+json for error responses of the framework (404, 405,
+// 500, CORS, typed handler errors) instead of plain text
+func EnableProblemDetails() ServerOption {
+	return ServerOption{
+		Name: PROBLEM_DETAILS,
+	}
+}
+
+// Title, version, contact, license and servers of the OpenAPI description
+func WithOpenAPIInfo(info OpenAPIInfo) ServerOption {
+	return ServerOption{
+		Name: OPENAPI_INFO,
+		Data: info,
+	}
+}
+
+// Register a security scheme for routes with WithSecurity(name) and the OpenAPI description
+func WithSecurityScheme(name string, scheme SecurityScheme) ServerOption {
+	return ServerOption{
+		Name:  SECURITY_SCHEME,
+		Value: name,
+		Data:  scheme,
+	}
+}
+
 func (s *Server) initServerOptions() error {
 	for _, option := range s.Options {
 		switch option.Name {
 		case TRANSLATIONS_ENABLED:
 			s.TranslationsEnabled = true
 		case TRANSLATIONS_ADD:
-			data := readTranslationFile(option.Filename)
+			data, err := readTranslationFile(option.Filename)
+			if err != nil {
+				return fmt.Errorf("invalid translation file %q for language %q: %w", option.Filename, option.Value, err)
+			}
 			s.Languages[option.Value] = data
 		case TRANSLATION_DEFAULT:
 			s.DefaultLanguage = option.Value
@@ -162,28 +214,58 @@ func (s *Server) initServerOptions() error {
 				return fmt.Errorf("invalid max header bytes %q: %w", option.Value, err)
 			}
 			s.MaxHeaderBytes = n
+		case MAX_BODY_BYTES:
+			n, err := strconv.ParseInt(option.Value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid max body bytes %q: %w", option.Value, err)
+			}
+			s.MaxBodyBytes = n
+		case PROBLEM_DETAILS:
+			s.ProblemDetails = true
+		case CORS:
+			config, ok := option.Data.(CORSConfig)
+			if !ok {
+				return fmt.Errorf("invalid cors config %T", option.Data)
+			}
+			s.CORS = &config
+		case OPENAPI_INFO:
+			info, ok := option.Data.(OpenAPIInfo)
+			if !ok {
+				return fmt.Errorf("invalid openapi info %T", option.Data)
+			}
+			s.OpenAPIInfo = info
+		case REQUEST_VALIDATION:
+			s.RequestValidation = true
+		case RESPONSE_VALIDATION:
+			s.ResponseValidation = option.Value == "enable"
+		case SECURITY_SCHEME:
+			scheme, ok := option.Data.(SecurityScheme)
+			if !ok {
+				return fmt.Errorf("invalid security scheme %q: %T", option.Value, option.Data)
+			}
+			s.SecuritySchemes[option.Value] = scheme
 		}
 	}
 	return nil
 }
 
-func readTranslationFile(filepath string) map[string]string {
+func readTranslationFile(filepath string) (map[string]string, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer file.Close()
 
 	content, err := io.ReadAll(file)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	var data map[string]string
 	err = json.Unmarshal(content, &data)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return data
+	return data, nil
 }
@@ -7,7 +7,19 @@ import (
 )
 
 func TestReadTranslationFile(t *testing.T) {
-	res := readTranslationFile("en_test.json")
+	res, err := readTranslationFile("en_test.json")
+	assert.NoError(t, err)
 	assert.Equal(t, 1, len(res))
 	assert.Equal(t, "Test String", res["test_str"])
+
+	res, err = readTranslationFile("missing_test.json")
+	assert.Nil(t, res)
+	assert.Error(t, err)
+}
+
+func TestMissingTranslationFileReturnsError(t *testing.T) {
+	s, err := NewServer(AddTranslationFile("fr", "missing_test.json"))
+	assert.Nil(t, s)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing_test.json")
 }
@@ -8,16 +8,22 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/loissascha/go-http-server/respond"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -152,6 +158,802 @@ func TestUnsupportedMethodReturnsMethodNotAllowed(t *testing.T) {
 	assert.NoError(t, err)
 	defer resp.Body.Close()
 	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "GET, HEAD, OPTIONS", resp.Header.Get("Allow"))
+}
+
+func TestPATCHRouteAndAutomaticHEAD(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GETI("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Items", "1")
+		w.Write([]byte("items"))
+	})
+	s.PATCHI("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	err = s.setupHandlers()
+	assert.NoError(t, err)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPatch, ts.URL+"/items", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, err = http.Head(ts.URL + "/items")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-Items"))
+	assert.Empty(t, body)
+}
+
+func TestUserDefinedOPTIONSHandler(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "*")
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.OPTIONSI("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	})
+	err = s.setupHandlers()
+	assert.NoError(t, err)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodOptions, ts.URL+"/items", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "GET, OPTIONS", resp.Header.Get("Allow"))
+
+	// preflights are still answered by the cors middleware
+	req, err = http.NewRequest(http.MethodOptions, ts.URL+"/items", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Allow"))
+}
+
+func TestCustomMethodRoute(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Route("PROPFIND", "/files/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("name")))
+	})
+	assert.Equal(t, Method("PROPFIND"), s.Paths["/files/{name}"][0].Method)
+	err = s.setupHandlers()
+	assert.NoError(t, err)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	req, err := http.NewRequest("PROPFIND", ts.URL+"/files/report.txt", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "report.txt", string(body))
+
+	resp, err = http.Get(ts.URL + "/files/report.txt")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "OPTIONS, PROPFIND", resp.Header.Get("Allow"))
+}
+
+func TestMethodNotAllowedAndAutomaticOPTIONS(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.POSTI("/login", testRoute)
+	s.GETI("/users", testRoute)
+	s.DELETEI("/users", testRoute)
+	err = s.setupHandlers()
+	assert.NoError(t, err)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/login")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "OPTIONS, POST", resp.Header.Get("Allow"))
+
+	req, err := http.NewRequest(http.MethodOptions, ts.URL+"/users", nil)
+	assert.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", resp.Header.Get("Allow"))
+}
+
+func TestRouteGroups(t *testing.T) {
+	type groupType struct {
+		Name string `json:"name"`
+	}
+	calls := []string{}
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s, err := NewServer()
+	assert.NoError(t, err)
+	api := s.Group("/api", WithMiddlewares(mw("api")), WithTags("api"))
+	v1 := api.Group("/v1/", WithMiddlewares(mw("v1")), WithExportType[groupType]())
+	v1.GET("/users", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}, WithMiddlewares(mw("route")), WithTags("users"))
+	v1.POSTI("/", testRoute)
+
+	assert.Contains(t, s.Paths, "/api/v1/users")
+	assert.Contains(t, s.Paths, "/api/v1")
+	info := s.Paths["/api/v1/users"][0].Info
+	assert.Equal(t, []string{"api", "users"}, info.Tags)
+	assert.Equal(t, 1, len(info.ExportTypes))
+	assert.Equal(t, 3, len(info.Middlewares))
+
+	err = s.setupHandlers()
+	assert.NoError(t, err)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/users")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"api", "v1", "route", "handler"}, calls)
+}
+
+func TestMountSubServerAndHandler(t *testing.T) {
+	admin, err := NewServer()
+	assert.NoError(t, err)
+	admin.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.PathValue("id")))
+	})
+
+	shop, err := NewServer(
+		EnableTranslations(),
+		SetDefaultLanguage("en"),
+		AddTranslationFile("en", "en_test.json"),
+	)
+	assert.NoError(t, err)
+	shop.GET("/cart", testRoute)
+
+	s, err := NewServer(
+		EnableTranslations(),
+		SetDefaultLanguage("en"),
+		AddTranslationFile("en", "en_test.json"),
+		AddTranslationFile("de", "de_test.json"),
+	)
+	assert.NoError(t, err)
+	s.Mount("/admin", admin)
+	s.Mount("/shop/", shop)
+	s.Mount("/debug", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("debug " + r.URL.Path))
+	}))
+
+	paths := s.allPaths()
+	assert.Contains(t, paths, "/admin/users/{id}")
+	assert.Equal(t, "/admin/users/{id}", paths["/admin/users/{id}"][0].Route)
+	assert.Contains(t, paths, "/shop/en/cart")
+
+	err = s.setupHandlers()
+	assert.NoError(t, err)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	for _, url := range []string{"/admin/users/7", "/de/admin/users/7"} {
+		resp, err := http.Get(ts.URL + url)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "/users/7 7", string(body))
+	}
+
+	resp, err := http.Get(ts.URL + "/debug/pprof")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "debug /pprof", string(body))
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = client.Get(ts.URL + "/shop/cart")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/shop/en/cart", resp.Header.Get("Location"))
+
+	resp, err = http.Get(ts.URL + "/shop/en/cart")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+}
+
+func TestGlobalMiddlewares(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "*")
+	calls := []string{}
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				w.Header().Add("X-Seen-CORS", w.Header().Get("Access-Control-Allow-Origin"))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Handle("/raw", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	s.GETI("/route", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}, WithMiddlewares(mw("route")))
+	s.UseAfter(mw("after"))
+	s.Use(mw("use"))
+	s.UseBefore(mw("before"))
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/route", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "http://example.com")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{"before", "use", "route", "after", "handler"}, calls)
+	assert.Equal(t, []string{"", "http://example.com", "http://example.com", "http://example.com"}, resp.Header.Values("X-Seen-CORS"))
+
+	for _, url := range []string{"/raw", "/unknown"} {
+		calls = []string{}
+		resp, err = http.Get(ts.URL + url)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []string{"before", "use", "after"}, calls[:3])
+	}
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	calls = []string{}
+	resp, err = http.Post(ts.URL+"/route", "text/plain", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, []string{"before", "use", "after"}, calls)
+}
+
+func TestCORSConfigOption(t *testing.T) {
+	s, err := NewServer(WithCORS(CORSConfig{
+		AllowedOrigins:      []string{"https://*.example.com"},
+		AllowedMethods:      []string{"GET"},
+		AllowedHeaders:      []string{"Authorization"},
+		ExposedHeaders:      []string{"X-Total"},
+		MaxAge:              10 * time.Minute,
+		AllowPrivateNetwork: true,
+	}))
+	assert.NoError(t, err)
+	s.GETI("/public", testRoute)
+	s.GETI("/internal", testRoute, WithRouteCORS(CORSConfig{
+		AllowedOrigins: []string{"https://intranet.local"},
+	}))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	preflight := func(route, origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, ts.URL+route, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Private-Network", "true")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := preflight("/public", "https://app.eu.example.com")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://app.eu.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", resp.Header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Private-Network"))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+
+	resp = preflight("/public", "https://example.com")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = preflight("/public", "https://evil.com/.example.com")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = preflight("/public", "http://localhost:5173")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = preflight("/internal", "https://app.example.com")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = preflight("/internal", "https://intranet.local")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	// configs without methods and headers use the defaults
+	assert.Equal(t, "POST, GET, OPTIONS, PUT, DELETE, PATCH, HEAD", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Content-Type")
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/public", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total", resp.Header.Get("Access-Control-Expose-Headers"))
+}
+
+func TestCORSFromEnvRequiresOriginsInProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("ALLOWED_ORIGINS", "")
+	s, err := NewServer()
+	assert.Nil(t, s)
+	assert.Error(t, err)
+
+	s, err = NewServer(WithCORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	assert.NotNil(t, s)
+	assert.NoError(t, err)
+}
+
+func TestRegistrationErrors(t *testing.T) {
+	s, err := NewServer(
+		EnableTranslations(),
+		SetDefaultLanguage("en"),
+		AddTranslationFile("en", "en_test.json"),
+	)
+	assert.NoError(t, err)
+	s.GET("/users", testRoute)
+	s.POST("/users", testRoute)
+	assert.NoError(t, s.Validate())
+
+	s.GETI("/en/users", testRoute)
+	s.Route("BAD METHOD", "/files", testRoute)
+
+	err = s.Validate()
+	assert.Error(t, err)
+	var conflict *RouteConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, "/en/users", conflict.First.Route)
+	assert.Equal(t, METHOD_GET, conflict.Second.Method)
+	assert.Contains(t, conflict.First.Location, "server_test.go")
+	assert.Contains(t, conflict.Second.Location, "server_test.go")
+	assert.NotEqual(t, conflict.First.Location, conflict.Second.Location)
+	var invalidMethod *InvalidMethodError
+	assert.True(t, errors.As(err, &invalidMethod))
+	assert.Equal(t, "/files", invalidMethod.Registration.Route)
+	assert.Equal(t, 2, len(s.Paths["/en/users"])) // GET and POST, the second GET was rejected
+
+	err = s.Serve(getFreeAddr(t))
+	assert.ErrorAs(t, err, &conflict)
+}
+
+func TestHandleMethodPatternNotFound(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Handle("GET /raw", func(w http.ResponseWriter, r *http.Request) {})
+	s.Handle("/dir/", func(w http.ResponseWriter, r *http.Request) {})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/raw", "text/plain", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "GET, HEAD", resp.Header.Get("Allow"))
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = client.Get(ts.URL + "/dir")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "/dir/", resp.Header.Get("Location"))
+
+	resp, err = http.Get(ts.URL + "/unknown")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, string(body), "404 page not found")
+}
+
+func TestPatternConflicts(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GET("/users/{id}", testRoute)
+	s.POST("/users/{name}", testRoute)
+	s.GET("/admin/", testRoute)
+	s.Mount("/admin", http.NotFoundHandler())
+	s.Mount("/files", http.NotFoundHandler())
+	s.GET("/files/{path...}", testRoute)
+
+	var conflicts []*RouteConflictError
+	for _, err := range s.Validate().(interface{ Unwrap() []error }).Unwrap() {
+		var conflict *RouteConflictError
+		if errors.As(err, &conflict) {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	assert.Len(t, conflicts, 3)
+	assert.Equal(t, "/users/{id}", conflicts[0].First.Route)
+	assert.Equal(t, "/users/{name}", conflicts[0].Second.Route)
+	assert.Equal(t, "/admin/", conflicts[1].First.Route)
+	assert.Equal(t, "/admin/", conflicts[1].Second.Route)
+	assert.Equal(t, "/files/", conflicts[2].First.Route)
+	assert.Equal(t, "/files/{path...}", conflicts[2].Second.Route)
+
+	// requests keep failing with 500 instead of blocking
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	for range 2 {
+		resp, err := http.Get(ts.URL + "/users/1")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+}
+
+func TestHandlePatternConflict(t *testing.T) {
+	mux := http.NewServeMux()
+	assert.NoError(t, handlePattern(mux, "/a/{x}", http.NotFoundHandler()))
+	assert.Error(t, handlePattern(mux, "/{y}/b", http.NotFoundHandler()))
+}
+
+type typedInput struct {
+	ID      int      `path:"id" json:"-"`
+	Verbose bool     `query:"verbose" json:"-"`
+	Tags    []string `query:"tag" json:"-"`
+	Token   string   `header:"X-Token" json:"-"`
+	Name    string   `json:"name"`
+}
+
+type typedOutput struct {
+	Message string `json:"message"`
+}
+
+func TestTypedHandlerPointerInput(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	Handle(s, http.MethodPost, "/users/{id}", func(ctx context.Context, in *typedInput) (typedOutput, error) {
+		return typedOutput{Message: fmt.Sprintf("%d %s", in.ID, in.Name)}, nil
+	})
+	Handle(s, http.MethodGet, "/count", func(ctx context.Context, in int) (int, error) {
+		return in, nil
+	})
+
+	var invalidInput *InvalidInputTypeError
+	assert.ErrorAs(t, s.Validate(), &invalidInput)
+	assert.Equal(t, reflect.TypeFor[int](), invalidInput.Type)
+	assert.Equal(t, "/count", invalidInput.Registration.Route)
+	assert.NotContains(t, s.Paths, "/count")
+
+	assert.Equal(t, reflect.TypeFor[typedInput](), s.Paths["/users/{id}"][0].Info.ExportTypes[0])
+	schemas := newOpenAPISchemas()
+	operation := createOperation(&s.Paths["/users/{id}"][0], "1", schemas)
+	assert.Equal(t, "#/components/schemas/typedInput", operation.RequestBody.Content["application/json"].Schema["$ref"])
+
+	s.registrationErrors = nil
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/users/5", "application/json", bytes.NewBufferString(`{"name": "Ann"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"message": "5 Ann"}`, string(body))
+}
+
+func TestTypedHandler(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	Handle(s, http.MethodPost, "/users/{id}", func(ctx context.Context, in typedInput) (typedOutput, error) {
+		if in.Name == "" {
+			return typedOutput{}, NewHTTPError(http.StatusConflict, "name missing")
+		}
+		if in.Name == "crash" {
+			return typedOutput{}, errors.New("database password leaked")
+		}
+		return typedOutput{Message: fmt.Sprintf("%d %v %v %s %s", in.ID, in.Verbose, in.Tags, in.Token, in.Name)}, nil
+	}, WithSummary("Update user"))
+
+	info := s.Paths["/users/{id}"][0].Info
+	assert.Equal(t, 2, len(info.ExportTypes))
+	assert.Equal(t, reflect.TypeFor[typedInput](), info.RequestType)
+	assert.Equal(t, reflect.TypeFor[typedOutput](), info.ResponseTypes["200"])
+	assert.Equal(t, 4, len(info.Params))
+
+	schemas := newOpenAPISchemas()
+	operation := createOperation(&s.Paths["/users/{id}"][0], "1", schemas)
+	assert.Equal(t, "Update user", operation.Summary)
+	assert.Equal(t, "#/components/schemas/typedInput", operation.RequestBody.Content["application/json"].Schema["$ref"])
+	assert.NotContains(t, schemas.schemas["typedInput"], "required")
+	assert.Contains(t, schemas.schemas["typedOutput"]["properties"], "message")
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(url string, body string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+url, bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("X-Token", "secret")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	status, body := post("/users/7?verbose=true&tag=a&tag=b", `{"name":"anna"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"message":"7 true [a b] secret anna"}`, body)
+
+	status, body = post("/users/7", `{}`)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, `{"error":"name missing"}`, body)
+
+	status, body = post("/users/7", `{"name":"crash"}`)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.NotContains(t, body, "password")
+
+	status, _ = post("/users/seven", `{"name":"anna"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = post("/users/7", `{"name":`)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+type bindPaging struct {
+	Page  int    `query:"page"`
+	Order string `header:"X-Order" json:"order"`
+}
+
+type BindFilter struct {
+	Status string `query:"status"`
+}
+
+type bindInput struct {
+	bindPaging
+	*BindFilter
+	ID    string `path:"id" query:"id"`
+	Owner string `query:"owner"`
+	Name  string `json:"name"`
+}
+
+func TestBindEmbeddedAndSourceFields(t *testing.T) {
+	bind := func(url string, body string, pathID string) (bindInput, error) {
+		r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", pathID)
+		in := bindInput{}
+		err := Bind(r, &in)
+		return in, err
+	}
+
+	in, err := bind("/items?page=2&status=open", `{"name":"anna"}`, "7")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, in.Page)
+	assert.Equal(t, "open", in.BindFilter.Status)
+	assert.Equal(t, "7", in.ID)
+	assert.Equal(t, "anna", in.Name)
+
+	// empty path values fall back to the field's other tags
+	in, err = bind("/items?id=9", `{}`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "9", in.ID)
+	assert.Nil(t, in.BindFilter)
+
+	// source tagged fields can't be set from the body
+	in, err = bind("/items", `{"Owner":"mallory","order":"desc","Page":5,"Status":"closed","name":"anna"}`, "")
+	assert.NoError(t, err)
+	assert.Empty(t, in.Owner)
+	assert.Empty(t, in.Order)
+	assert.Zero(t, in.Page)
+	assert.Empty(t, in.BindFilter.Status)
+	assert.Equal(t, "anna", in.Name)
+}
+
+type lockedError struct {
+	status int
+}
+
+func (e *lockedError) Error() string {
+	return "record locked"
+}
+
+func (e *lockedError) StatusCode() int {
+	return e.status
+}
+
+func TestWriteErrorMessages(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+
+	write := func(err error) (int, string) {
+		w := httptest.NewRecorder()
+		s.WriteError(w, httptest.NewRequest(http.MethodGet, "/", nil), err)
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	status, body := write(fmt.Errorf("update users set password: %w", &lockedError{status: http.StatusConflict}))
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, `{"error":"record locked"}`, body)
+
+	status, body = write(fmt.Errorf("db down: %w", &lockedError{status: http.StatusServiceUnavailable}))
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, `{"error":"Service Unavailable"}`, body)
+
+	status, body = write(fmt.Errorf("maintenance: %w", NewHTTPError(http.StatusServiceUnavailable, "back at noon")))
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, `{"error":"back at noon"}`, body)
+}
+
+func TestProblemDetailsResponses(t *testing.T) {
+	s, err := NewServer(
+		EnableProblemDetails(),
+		EnableTranslations(),
+		SetDefaultLanguage("en"),
+		AddTranslationFile("en", "en_test.json"),
+		AddTranslationFile("de", "de_test.json"),
+	)
+	assert.NoError(t, err)
+	s.GET("/users", testRoute)
+	s.GET("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	problem := func(method, url string) (int, string, map[string]any) {
+		req, err := http.NewRequest(method, ts.URL+url, nil)
+		assert.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body := map[string]any{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, resp.Header.Get("Content-Type"), body
+	}
+
+	status, contentType, body := problem(http.MethodPost, "/de/users")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+	assert.Equal(t, "application/problem+json", contentType)
+	assert.Equal(t, map[string]any{
+		"type":     "about:blank",
+		"title":    "Methode nicht erlaubt",
+		"status":   float64(405),
+		"detail":   "Method POST Not Allowed",
+		"instance": "/de/users",
+	}, body)
+
+	status, _, body = problem(http.MethodPost, "/en/users")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+	assert.Equal(t, "Method Not Allowed", body["title"])
+
+	status, _, body = problem(http.MethodGet, "/unknown")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "Not Found", body["title"])
+
+	status, _, body = problem(http.MethodGet, "/en/panic")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "Internal Server Error", body["title"])
+}
+
+func TestSSEStreamAndShutdown(t *testing.T) {
+	s, err := NewServer(SetWriteTimeout(50 * time.Millisecond))
+	assert.NoError(t, err)
+	s.GETI("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	s.SSE("/events", func(ctx context.Context, stream *respond.SSEStream) error {
+		stream.Send(respond.SSEEvent{ID: "2", Event: "resume", Data: stream.LastEventID(), Retry: 3 * time.Second})
+		stream.Send(respond.SSEEvent{Data: map[string]int{"count": 1}})
+		stream.Send(respond.SSEEvent{Data: "line 1\nline 2"})
+		// outlive the WriteTimeout of the server
+		time.Sleep(100 * time.Millisecond)
+		stream.Comment("still here")
+		<-ctx.Done()
+		return nil
+	})
+
+	paths := createPathItem("/events", s.Paths["/events"], newOpenAPISchemas(), map[string]bool{})
+	assert.Contains(t, paths["get"].(OpenAPIPath).Responses["200"].Content, "text/event-stream")
+
+	addr := getFreeAddr(t)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(addr)
+	}()
+	waitForServer(t, addr, "http", "/health", nil)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/events", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	expected := "id: 2\nevent: resume\nretry: 3000\ndata: 1\n\n" +
+		"data: {\"count\":1}\n\n" +
+		"data: line 1\ndata: line 2\n\n" +
+		": still here\n\n"
+	buf := make([]byte, len(expected))
+	_, err = io.ReadFull(resp.Body, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(buf))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	assert.NoError(t, s.Shutdown(shutdownCtx))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.NoError(t, <-errCh)
+}
+
+func TestSSEMountedShutdown(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GETI("/health", func(w http.ResponseWriter, r *http.Request) {})
+	child, err := NewServer()
+	assert.NoError(t, err)
+	done := make(chan struct{})
+	child.SSE("/events", func(ctx context.Context, stream *respond.SSEStream) error {
+		stream.Heartbeat(time.Millisecond)
+		stream.Comment("open")
+		<-ctx.Done()
+		close(done)
+		return nil
+	})
+	s.Mount("/api", child)
+
+	addr := getFreeAddr(t)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(addr)
+	}()
+	waitForServer(t, addr, "http", "/health", nil)
+
+	resp, err := http.Get("http://" + addr + "/api/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	buf := make([]byte, len(": open\n\n"))
+	_, err = io.ReadFull(resp.Body, buf)
+	assert.NoError(t, err)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(shutdownCtx))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream of the mounted server wasn't closed")
+	}
+	assert.NoError(t, <-errCh)
 }
 
 func TestServerTimeoutOptions(t *testing.T) {
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/loissascha/go-http-server/respond"
+	"github.com/loissascha/go-logger/logger"
+)
+
+// Server-Sent Events route. The stream context is done when the client disconnects or the
+// server (or a server it is mounted in) shuts down. Shutdown cancels the stream contexts
+// and waits for h to return, so h has to return once ctx is done.
+// The stream is closed when h returns.
+func (s *Server) SSE(route string, h func(ctx context.Context, stream *respond.SSEStream) error, opts ...RouteOption) {
+	s.GET(route, func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		for server := s; server != nil; server = server.parent {
+			stop := context.AfterFunc(server.streamsContext(), cancel)
+			defer stop()
+		}
+
+		stream, err := respond.SSE(w, r.WithContext(ctx))
+		if err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		defer stream.Close()
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		err = h(ctx, stream)
+		if err != nil && ctx.Err() == nil {
+			logger.Error(nil, "SSE stream {route} failed: {error}", route, err)
+		}
+	}, append(opts, withEventStreamResponse())...)
+}
+
+// documents the route response as text/event-stream
+func withEventStreamResponse() RouteOption {
+	return func(ri *RouteInfo) {
+		response, found := ri.Responses["200"]
+		if !found {
+			response.Description = "Event stream"
+		}
+		response.Content = map[string]OpenAPIMediaType{
+			"text/event-stream": {Schema: map[string]any{"type": "string"}},
+		}
+		ri.Responses["200"] = response
+	}
+}
+
+// done when the running server shuts down
+func (s *Server) streamsContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streamsCtx == nil {
+		return context.Background()
+	}
+	return s.streamsCtx
+}
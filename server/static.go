@@ -0,0 +1,284 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration of a static file route (see StaticOption)
+type StaticConfig struct {
+	// Serve the index file for unknown paths (single page applications)
+	SPA bool
+	// Index file of directories, default index.html
+	Index string
+	// List the files of directories without index file
+	DirectoryListing bool
+	// Cache-Control values per glob, the first matching rule wins
+	CacheControl []StaticCacheRule
+}
+
+// Cache-Control value for files matching the glob. Globs without "/" match the file name,
+// other globs the path relative to the static root (see path.Match).
+type StaticCacheRule struct {
+	Glob  string
+	Value string
+}
+
+type StaticOption func(*StaticConfig)
+
+// Serve the index file for unknown paths
+func WithSPA() StaticOption {
+	return func(sc *StaticConfig) {
+		sc.SPA = true
+	}
+}
+
+// Index file of directories (and SPA fallback)
+func WithIndex(name string) StaticOption {
+	return func(sc *StaticConfig) {
+		sc.Index = name
+	}
+}
+
+// List the files of directories without index file (off by default)
+func WithDirectoryListing() StaticOption {
+	return func(sc *StaticConfig) {
+		sc.DirectoryListing = true
+	}
+}
+
+// Cache-Control value for files matching the glob, e.g. WithCacheControl("*.js", "public, max-age=31536000, immutable")
+func WithCacheControl(glob string, value string) StaticOption {
+	return func(sc *StaticConfig) {
+		sc.CacheControl = append(sc.CacheControl, StaticCacheRule{Glob: glob, Value: value})
+	}
+}
+
+// Serve the files of fsys (e.g. an embed.FS or os.DirFS) below prefix. Supports ETags,
+// conditional and range requests and precompressed .br/.gz siblings of files. With
+// translations enabled the files are served below every language prefix as well.
+func (s *Server) Static(prefix string, fsys fs.FS, opts ...StaticOption) {
+	config := StaticConfig{Index: "index.html"}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	fh := &staticHandler{
+		server: s,
+		fsys:   fsys,
+		config: config,
+	}
+
+	prefix = strings.TrimSuffix(joinRoute("", prefix), "/")
+	// files aren't part of the OpenAPI description (and its request validation)
+	s.GETI(prefix+"/{path...}", fh.serve, WithHidden())
+	if s.TranslationsEnabled {
+		for short := range s.Languages {
+			s.GETI(fmt.Sprintf("/%s%s/{path...}", short, prefix), fh.serve, WithHidden())
+		}
+	}
+}
+
+type staticHandler struct {
+	server *Server
+	fsys   fs.FS
+	config StaticConfig
+	etags  sync.Map
+}
+
+var staticEncodings = []struct{ name, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type staticETagKey struct {
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+func (fh *staticHandler) serve(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.PathValue("path")), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(fh.fsys, name)
+	if err == nil && info.IsDir() {
+		index := path.Join(name, fh.config.Index)
+		indexInfo, indexErr := fs.Stat(fh.fsys, index)
+		hasIndex := indexErr == nil && !indexInfo.IsDir()
+		if (hasIndex || fh.config.DirectoryListing) && !strings.HasSuffix(r.URL.Path, "/") {
+			// relative links of the page resolve against the directory only with a trailing slash
+			staticDirectoryRedirect(w, r)
+			return
+		}
+		if hasIndex {
+			fh.serveFile(w, r, index)
+			return
+		}
+		if fh.config.DirectoryListing {
+			fh.serveDirectory(w, r, name)
+			return
+		}
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && fh.config.SPA {
+			fh.serveFile(w, r, fh.config.Index)
+			return
+		}
+		fh.server.writeError(w, r, http.StatusNotFound, "404 page not found")
+		return
+	}
+	fh.serveFile(w, r, name)
+}
+
+func (fh *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	for _, rule := range fh.config.CacheControl {
+		target := name
+		if !strings.Contains(rule.Glob, "/") {
+			target = path.Base(name)
+		}
+		if matched, _ := path.Match(rule.Glob, target); matched {
+			w.Header().Set("Cache-Control", rule.Value)
+			break
+		}
+	}
+
+	// precompressed siblings
+	for _, encoding := range staticEncodings {
+		if acceptsEncoding(r, encoding.name) && fh.serveContent(w, r, name+encoding.ext, name, encoding.name) {
+			return
+		}
+	}
+	if !fh.serveContent(w, r, name, name, "") {
+		fh.server.writeError(w, r, http.StatusNotFound, "404 page not found")
+	}
+}
+
+// serves the file with http.ServeContent, returns false if the file doesn't exist
+func (fh *staticHandler) serveContent(w http.ResponseWriter, r *http.Request, file string, name string, encoding string) bool {
+	f, err := fh.fsys.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	var content io.ReadSeeker
+	if rs, ok := f.(io.ReadSeeker); ok {
+		content = rs
+	} else {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return false
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := fh.etag(file, info, content)
+	if err != nil {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return true
+}
+
+// strong ETag from the content hash, cached per file version
+func (fh *staticHandler) etag(file string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := staticETagKey{name: file, modTime: info.ModTime(), size: info.Size()}
+	if etag, ok := fh.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+	h := sha256.New()
+	_, err := io.Copy(h, content)
+	if err != nil {
+		return "", err
+	}
+	_, err = content.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	fh.etags.Store(key, etag)
+	return etag, nil
+}
+
+func (fh *staticHandler) serveDirectory(w http.ResponseWriter, r *http.Request, name string) {
+	entries, err := fs.ReadDir(fh.fsys, name)
+	if err != nil {
+		fh.server.writeError(w, r, http.StatusNotFound, "404 page not found")
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(entryName), html.EscapeString(entryName))
+	}
+	b.WriteString("</pre>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, b.String())
+}
+
+// redirects to the path with a trailing slash. The location is relative like the one of
+// http.FileServer, so it works below mount prefixes too.
+func staticDirectoryRedirect(w http.ResponseWriter, r *http.Request) {
+	location := path.Base(r.URL.Path) + "/"
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	// http.Redirect would resolve the location against the stripped request path
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// true if the Accept-Encoding header lists the encoding without q=0
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for part := range strings.SplitSeq(value, ",") {
+			token, params, _ := strings.Cut(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(token), encoding) {
+				continue
+			}
+			q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+			if ok && strings.Trim(q, "0.") == "" {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStaticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>app</h1>")},
+		"app.js":          {Data: []byte("console.log('app')")},
+		"app.js.gz":       {Data: []byte("gzipped")},
+		"app.js.br":       {Data: []byte("brotli")},
+		"docs/readme.txt": {Data: []byte("readme")},
+	}
+}
+
+func staticGet(t *testing.T, url string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	// the transport would otherwise ask for (and decode) gzip itself
+	req.Header.Set("Accept-Encoding", "identity")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestStaticServesFilesWithETagAndCacheControl(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Static("/assets", testStaticFS(), WithCacheControl("*.js", "public, max-age=31536000, immutable"))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, body := staticGet(t, ts.URL+"/assets/app.js", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "console.log('app')", body)
+	assert.Equal(t, "public, max-age=31536000, immutable", resp.Header.Get("Cache-Control"))
+	assert.Contains(t, resp.Header.Get("Content-Type"), "javascript")
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	resp, _ = staticGet(t, ts.URL+"/assets/app.js", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, body = staticGet(t, ts.URL+"/assets/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "<h1>app</h1>", body)
+	assert.Empty(t, resp.Header.Get("Cache-Control"))
+
+	resp, _ = staticGet(t, ts.URL+"/assets/missing.js", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// no directory listing by default
+	resp, _ = staticGet(t, ts.URL+"/assets/docs/", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = staticGet(t, ts.URL+"/assets/../server.go", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStaticPrecompressedSiblings(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Static("/assets", testStaticFS())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, body := staticGet(t, ts.URL+"/assets/app.js", map[string]string{"Accept-Encoding": "gzip, br"})
+	assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "brotli", body)
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Contains(t, resp.Header.Get("Content-Type"), "javascript")
+
+	resp, body = staticGet(t, ts.URL+"/assets/app.js", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "gzipped", body)
+
+	resp, body = staticGet(t, ts.URL+"/assets/app.js", map[string]string{"Accept-Encoding": "identity"})
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "console.log('app')", body)
+}
+
+func TestStaticSPAFallbackAndDirectoryListing(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Static("/", testStaticFS(), WithSPA())
+	s.Static("/files", testStaticFS(), WithDirectoryListing())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, body := staticGet(t, ts.URL+"/settings/profile", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "<h1>app</h1>", body)
+
+	resp, body = staticGet(t, ts.URL+"/app.js", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "console.log('app')", body)
+
+	resp, body = staticGet(t, ts.URL+"/files/docs/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `<a href="readme.txt">readme.txt</a>`)
+}
+
+func TestStaticDirectoryRedirectsToTrailingSlash(t *testing.T) {
+	fsys := testStaticFS()
+	fsys["guide/index.html"] = &fstest.MapFile{Data: []byte(`<img src="logo.png">`)}
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Static("/files", fsys, WithDirectoryListing())
+	child, err := NewServer()
+	assert.NoError(t, err)
+	child.Static("/", fsys)
+	s.Mount("/site", child)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	redirect := func(url string) *http.Response {
+		resp, err := client.Get(ts.URL + url)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := redirect("/files/docs?sort=name")
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "docs/?sort=name", resp.Header.Get("Location"))
+	resp = redirect("/files/guide")
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "guide/", resp.Header.Get("Location"))
+	// relative to the full path of the mounted server
+	resp = redirect("/site/guide")
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "guide/", resp.Header.Get("Location"))
+
+	resp, body := staticGet(t, ts.URL+"/site/guide", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/site/guide/", resp.Request.URL.Path)
+	assert.Equal(t, `<img src="logo.png">`, body)
+
+	// directories without index file and listing stay unknown
+	resp = redirect("/site/docs")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStaticWithTranslations(t *testing.T) {
+	s, err := NewServer(
+		EnableTranslations(),
+		SetDefaultLanguage("en"),
+		AddTranslationFile("en", "en_test.json"),
+		AddTranslationFile("de", "de_test.json"),
+	)
+	assert.NoError(t, err)
+	s.Static("/assets", testStaticFS())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, url := range []string{"/assets/app.js", "/de/assets/app.js", "/en/assets/app.js"} {
+		resp, body := staticGet(t, ts.URL+url, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, url)
+		assert.Equal(t, "console.log('app')", body, url)
+	}
+}
+
+func TestStaticIsHiddenFromOpenAPI(t *testing.T) {
+	s, err := NewServer(EnableRequestValidation())
+	assert.NoError(t, err)
+	s.Static("/assets", testStaticFS())
+	s.GET("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	description := s.openAPIDescription()
+	assert.NotContains(t, description.Paths, "/assets/{path}")
+	assert.Contains(t, description.Paths, "/users")
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, body := staticGet(t, ts.URL+"/assets/app.js?unknown=1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "console.log('app')", body)
+}
@@ -0,0 +1,347 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loissascha/go-logger/logger"
+)
+
+const TUS_VERSION = "1.0.0"
+
+// Returned by TusStore implementations for unknown uploads
+var ErrTusNotFound = errors.New("tus upload not found")
+
+// State of a resumable upload
+type TusUpload struct {
+	ID       string
+	Size     int64
+	Offset   int64
+	Metadata map[string]string
+	// zero if the upload doesn't expire
+	ExpiresAt time.Time
+}
+
+func (u TusUpload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+// Storage of resumable uploads
+type TusStore interface {
+	// Create a new upload and assign its ID
+	Create(ctx context.Context, upload TusUpload) (TusUpload, error)
+	// Current state of the upload (ErrTusNotFound for unknown ids)
+	Info(ctx context.Context, id string) (TusUpload, error)
+	// Append data at offset and return the number of written bytes. Bytes written before an
+	// error (e.g. a disconnect) have to be kept so the client can resume.
+	WriteChunk(ctx context.Context, id string, offset int64, data io.Reader) (int64, error)
+	// Remove the upload
+	Terminate(ctx context.Context, id string) error
+}
+
+// Configuration of a tus endpoint (see WithTusConfig)
+type TusConfig struct {
+	// Maximum size of an upload (Tus-Max-Size), 0 for no limit
+	MaxSize int64
+	// Unfinished uploads expire after this duration (expiration extension), 0 disables it
+	Expiration time.Duration
+	// Called after the last chunk of an upload was written
+	OnComplete func(ctx context.Context, upload TusUpload)
+}
+
+// Configure a tus endpoint
+func WithTusConfig(config TusConfig) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.Tus = &config
+	}
+}
+
+// Resumable uploads (tus 1.0 core protocol with the creation, termination and expiration
+// extensions). Uploads are created with POST prefix and continued with HEAD and PATCH on
+// prefix/{id}. The routes use the middlewares of the server and opts (e.g. auth).
+func (s *Server) Tus(prefix string, store TusStore, opts ...RouteOption) {
+	info := getRouteInfos(opts...)
+	config := TusConfig{}
+	if info.Tus != nil {
+		config = *info.Tus
+	}
+	// marks the routes as tus routes, chunks are limited by MaxSize instead of MaxBodyBytes
+	opts = append(slices.Clone(opts), WithTusConfig(config))
+	th := &tusHandler{
+		server: s,
+		prefix: strings.TrimSuffix(joinRoute("", prefix), "/"),
+		store:  store,
+		config: config,
+	}
+
+	s.RouteI(http.MethodOptions, th.prefix, th.options, opts...)
+	s.RouteI(http.MethodPost, th.prefix, th.resumable(th.create), opts...)
+	s.RouteI(http.MethodOptions, th.prefix+"/{id}", th.options, opts...)
+	s.RouteI(http.MethodHead, th.prefix+"/{id}", th.resumable(th.head), opts...)
+	s.RouteI(http.MethodPatch, th.prefix+"/{id}", th.resumable(th.patch), opts...)
+	s.RouteI(http.MethodDelete, th.prefix+"/{id}", th.resumable(th.terminate), opts...)
+}
+
+type tusHandler struct {
+	server *Server
+	prefix string
+	store  TusStore
+	config TusConfig
+	locks  tusLocks
+}
+
+// ids of the uploads a PATCH is running for. Only requests in progress are kept, so
+// unknown and finished uploads don't grow the set.
+type tusLocks struct {
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+func (l *tusLocks) tryLock(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[id] {
+		return false
+	}
+	if l.active == nil {
+		l.active = map[string]bool{}
+	}
+	l.active[id] = true
+	return true
+}
+
+func (l *tusLocks) unlock(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.active, id)
+}
+
+func (th *tusHandler) extensions() string {
+	extensions := []string{"creation", "termination"}
+	if th.config.Expiration > 0 {
+		extensions = append(extensions, "expiration")
+	}
+	return strings.Join(extensions, ",")
+}
+
+func (th *tusHandler) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TUS_VERSION)
+	w.Header().Set("Tus-Version", TUS_VERSION)
+	w.Header().Set("Tus-Extension", th.extensions())
+	if th.config.MaxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(th.config.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checks the Tus-Resumable header of the request
+func (th *tusHandler) resumable(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", TUS_VERSION)
+		if r.Header.Get("Tus-Resumable") != TUS_VERSION {
+			w.Header().Set("Tus-Version", TUS_VERSION)
+			th.server.writeError(w, r, http.StatusPreconditionFailed, "unsupported tus version")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (th *tusHandler) create(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		th.server.writeError(w, r, http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if th.config.MaxSize > 0 && size > th.config.MaxSize {
+		th.server.writeError(w, r, http.StatusRequestEntityTooLarge, "upload exceeds Tus-Max-Size")
+		return
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		th.server.writeError(w, r, http.StatusBadRequest, "invalid Upload-Metadata")
+		return
+	}
+
+	upload := TusUpload{Size: size, Metadata: metadata}
+	if th.config.Expiration > 0 {
+		upload.ExpiresAt = time.Now().Add(th.config.Expiration).UTC()
+	}
+	upload, err = th.store.Create(r.Context(), upload)
+	if err != nil {
+		logger.Error(nil, "Creating tus upload failed: {error}", err)
+		th.server.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Location", mountPrefix(r)+th.prefix+"/"+upload.ID)
+	th.setExpires(w, upload)
+	w.WriteHeader(http.StatusCreated)
+	if upload.Complete() {
+		th.complete(r, upload)
+	}
+}
+
+func (th *tusHandler) head(w http.ResponseWriter, r *http.Request) {
+	upload, ok := th.upload(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", formatTusMetadata(upload.Metadata))
+	}
+	th.setExpires(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (th *tusHandler) patch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		th.server.writeError(w, r, http.StatusUnsupportedMediaType, "Content-Type has to be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		th.server.writeError(w, r, http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+
+	upload, ok := th.upload(w, r)
+	if !ok {
+		return
+	}
+	// one PATCH per upload at a time, the offset is read again while holding the lock
+	if !th.locks.tryLock(upload.ID) {
+		th.server.writeError(w, r, http.StatusLocked, "upload is locked by another request")
+		return
+	}
+	defer th.locks.unlock(upload.ID)
+	upload, ok = th.upload(w, r)
+	if !ok {
+		return
+	}
+	if offset != upload.Offset {
+		th.server.writeError(w, r, http.StatusConflict, fmt.Sprintf("Upload-Offset %d doesn't match the current offset %d", offset, upload.Offset))
+		return
+	}
+	remaining := upload.Size - upload.Offset
+	if r.ContentLength > remaining {
+		th.server.writeError(w, r, http.StatusRequestEntityTooLarge, "chunk exceeds Upload-Length")
+		return
+	}
+
+	n, err := th.store.WriteChunk(r.Context(), upload.ID, offset, io.LimitReader(r.Body, remaining))
+	upload.Offset += n
+	if err != nil && n == 0 {
+		logger.Error(nil, "Writing tus chunk failed: {error}", err)
+		th.server.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	th.setExpires(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+	if upload.Complete() {
+		th.complete(r, upload)
+	}
+}
+
+func (th *tusHandler) terminate(w http.ResponseWriter, r *http.Request) {
+	upload, ok := th.upload(w, r)
+	if !ok {
+		return
+	}
+	// uploads can't be removed while a PATCH writes to them
+	if !th.locks.tryLock(upload.ID) {
+		th.server.writeError(w, r, http.StatusLocked, "upload is locked by another request")
+		return
+	}
+	defer th.locks.unlock(upload.ID)
+	err := th.store.Terminate(r.Context(), upload.ID)
+	if err != nil {
+		logger.Error(nil, "Terminating tus upload failed: {error}", err)
+		th.server.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loads the upload of the request, expired uploads are removed (410)
+func (th *tusHandler) upload(w http.ResponseWriter, r *http.Request) (TusUpload, bool) {
+	upload, err := th.store.Info(r.Context(), r.PathValue("id"))
+	if errors.Is(err, ErrTusNotFound) {
+		th.server.writeError(w, r, http.StatusNotFound, "upload not found")
+		return upload, false
+	}
+	if err != nil {
+		logger.Error(nil, "Loading tus upload failed: {error}", err)
+		th.server.writeError(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return upload, false
+	}
+	if !upload.ExpiresAt.IsZero() && !upload.Complete() && time.Now().After(upload.ExpiresAt) {
+		th.store.Terminate(r.Context(), upload.ID)
+		th.server.writeError(w, r, http.StatusGone, "upload expired")
+		return upload, false
+	}
+	return upload, true
+}
+
+func (th *tusHandler) setExpires(w http.ResponseWriter, upload TusUpload) {
+	if !upload.ExpiresAt.IsZero() && !upload.Complete() {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (th *tusHandler) complete(r *http.Request, upload TusUpload) {
+	if th.config.OnComplete != nil {
+		th.config.OnComplete(r.Context(), upload)
+	}
+}
+
+// Upload-Metadata: comma separated "key base64(value)" pairs, the value is optional
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for pair := range strings.SplitSeq(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	keys := []string{}
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	pairs := []string{}
+	for _, key := range keys {
+		if metadata[key] == "" {
+			pairs = append(pairs, key)
+			continue
+		}
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(metadata[key])))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TusStore keeping every upload as <id>.bin (data) and <id>.json (state) in Dir
+type TusFileStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// Create the store and its directory
+func NewTusFileStore(dir string) (*TusFileStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &TusFileStore{Dir: dir}, nil
+}
+
+// Path of the data file of the upload
+func (fs *TusFileStore) Path(id string) string {
+	return filepath.Join(fs.Dir, id+".bin")
+}
+
+func (fs *TusFileStore) infoPath(id string) string {
+	return filepath.Join(fs.Dir, id+".json")
+}
+
+func (fs *TusFileStore) Create(ctx context.Context, upload TusUpload) (TusUpload, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return upload, err
+	}
+	upload.ID = hex.EncodeToString(id)
+	upload.Offset = 0
+
+	f, err := os.OpenFile(fs.Path(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return upload, err
+	}
+	f.Close()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return upload, fs.writeInfo(upload)
+}
+
+func (fs *TusFileStore) Info(ctx context.Context, id string) (TusUpload, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.readInfo(id)
+}
+
+func (fs *TusFileStore) WriteChunk(ctx context.Context, id string, offset int64, data io.Reader) (int64, error) {
+	if !validTusID(id) {
+		return 0, ErrTusNotFound
+	}
+	f, err := os.OpenFile(fs.Path(id), os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrTusNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	n, copyErr := io.Copy(f, data)
+	err = f.Close()
+	if copyErr != nil {
+		err = copyErr
+	}
+
+	// keep the written bytes even if the client disconnected
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	upload, infoErr := fs.readInfo(id)
+	if infoErr != nil {
+		return n, infoErr
+	}
+	upload.Offset = offset + n
+	infoErr = fs.writeInfo(upload)
+	if infoErr != nil {
+		return n, infoErr
+	}
+	return n, err
+}
+
+func (fs *TusFileStore) Terminate(ctx context.Context, id string) error {
+	if !validTusID(id) {
+		return ErrTusNotFound
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	err := os.Remove(fs.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrTusNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Remove(fs.Path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Remove all unfinished uploads that expired before now. Returns the number of removed uploads.
+func (fs *TusFileStore) RemoveExpired(ctx context.Context, now time.Time) (int, error) {
+	entries, err := os.ReadDir(fs.Dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		upload, err := fs.Info(ctx, id)
+		if err != nil {
+			continue
+		}
+		if upload.ExpiresAt.IsZero() || upload.Complete() || !now.After(upload.ExpiresAt) {
+			continue
+		}
+		err = fs.Terminate(ctx, id)
+		if err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// fs.mu has to be locked
+func (fs *TusFileStore) readInfo(id string) (TusUpload, error) {
+	upload := TusUpload{}
+	if !validTusID(id) {
+		return upload, ErrTusNotFound
+	}
+	data, err := os.ReadFile(fs.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return upload, ErrTusNotFound
+	}
+	if err != nil {
+		return upload, err
+	}
+	err = json.Unmarshal(data, &upload)
+	return upload, err
+}
+
+// fs.mu has to be locked
+func (fs *TusFileStore) writeInfo(upload TusUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := fs.infoPath(upload.ID) + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.infoPath(upload.ID))
+}
+
+// ids are generated by Create, anything else (e.g. path separators) is unknown
+func validTusID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
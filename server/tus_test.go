@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tusRequest(t *testing.T, method string, url string, body string, header map[string]string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Tus-Resumable", TUS_VERSION)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestTusUpload(t *testing.T) {
+	store, err := NewTusFileStore(t.TempDir())
+	assert.NoError(t, err)
+	completed := make(chan TusUpload, 1)
+
+	authCalls := atomic.Int32{}
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Tus("/uploads", store, WithTusConfig(TusConfig{
+		MaxSize:    1024,
+		Expiration: time.Hour,
+		OnComplete: func(ctx context.Context, upload TusUpload) {
+			completed <- upload
+		},
+	}), WithMiddlewares(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authCalls.Add(1)
+			next.ServeHTTP(w, r)
+		})
+	}))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp := tusRequest(t, http.MethodOptions, ts.URL+"/uploads", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "creation,termination,expiration", resp.Header.Get("Tus-Extension"))
+	assert.Equal(t, "1024", resp.Header.Get("Tus-Max-Size"))
+
+	resp = tusRequest(t, http.MethodPost, ts.URL+"/uploads", "", map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "11"})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	assert.Equal(t, TUS_VERSION, resp.Header.Get("Tus-Version"))
+
+	resp = tusRequest(t, http.MethodPost, ts.URL+"/uploads", "", map[string]string{"Upload-Length": "2048"})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp = tusRequest(t, http.MethodPost, ts.URL+"/uploads", "", map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": "filename aGVsbG8udHh0,private",
+	})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Upload-Expires"))
+	location := resp.Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/uploads/"))
+	id := strings.TrimPrefix(location, "/uploads/")
+
+	chunk := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	resp = tusRequest(t, http.MethodPatch, ts.URL+location, "hello ", chunk)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+
+	// resuming at the wrong offset
+	resp = tusRequest(t, http.MethodPatch, ts.URL+location, "world", chunk)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = tusRequest(t, http.MethodHead, ts.URL+location, "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+	assert.Equal(t, "11", resp.Header.Get("Upload-Length"))
+	assert.Equal(t, "filename aGVsbG8udHh0,private", resp.Header.Get("Upload-Metadata"))
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	chunk["Upload-Offset"] = "6"
+	resp = tusRequest(t, http.MethodPatch, ts.URL+location, "world", chunk)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "11", resp.Header.Get("Upload-Offset"))
+
+	upload := <-completed
+	assert.Equal(t, id, upload.ID)
+	assert.Equal(t, "hello.txt", upload.Metadata["filename"])
+	data, err := os.ReadFile(store.Path(id))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	resp = tusRequest(t, http.MethodDelete, ts.URL+location, "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = tusRequest(t, http.MethodHead, ts.URL+location, "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = tusRequest(t, http.MethodHead, ts.URL+"/uploads/../../etc", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Greater(t, authCalls.Load(), int32(8))
+}
+
+func TestTusExpiration(t *testing.T) {
+	store, err := NewTusFileStore(t.TempDir())
+	assert.NoError(t, err)
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Tus("/uploads", store, WithTusConfig(TusConfig{Expiration: time.Hour}))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp := tusRequest(t, http.MethodPost, ts.URL+"/uploads", "", map[string]string{"Upload-Length": "5"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	location := resp.Header.Get("Location")
+
+	removed, err := store.RemoveExpired(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	removed, err = store.RemoveExpired(context.Background(), time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	resp = tusRequest(t, http.MethodHead, ts.URL+location, "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestTusPatchUnknownUpload(t *testing.T) {
+	store, err := NewTusFileStore(t.TempDir())
+	assert.NoError(t, err)
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Tus("/uploads", store)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	chunk := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	resp := tusRequest(t, http.MethodPatch, ts.URL+"/uploads/unknown", "data", chunk)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestTusLocks(t *testing.T) {
+	locks := tusLocks{}
+	assert.True(t, locks.tryLock("a"))
+	assert.False(t, locks.tryLock("a"))
+	assert.True(t, locks.tryLock("b"))
+	locks.unlock("a")
+	locks.unlock("b")
+	assert.Empty(t, locks.active)
+	assert.True(t, locks.tryLock("a"))
+}
+
+// blocks WriteChunk until release is closed
+type blockingTusStore struct {
+	*TusFileStore
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingTusStore) WriteChunk(ctx context.Context, id string, offset int64, data io.Reader) (int64, error) {
+	close(s.writing)
+	<-s.release
+	return s.TusFileStore.WriteChunk(ctx, id, offset, data)
+}
+
+func TestTusTerminateDuringPatch(t *testing.T) {
+	fileStore, err := NewTusFileStore(t.TempDir())
+	assert.NoError(t, err)
+	store := &blockingTusStore{TusFileStore: fileStore, writing: make(chan struct{}), release: make(chan struct{})}
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Tus("/uploads", store)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp := tusRequest(t, http.MethodPost, ts.URL+"/uploads", "", map[string]string{"Upload-Length": "5"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	location := resp.Header.Get("Location")
+
+	patched := make(chan *http.Response, 1)
+	go func() {
+		chunk := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+		patched <- tusRequest(t, http.MethodPatch, ts.URL+location, "hello", chunk)
+	}()
+	<-store.writing
+
+	resp = tusRequest(t, http.MethodDelete, ts.URL+location, "", nil)
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+
+	close(store.release)
+	resp = <-patched
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Upload-Offset"))
+
+	resp = tusRequest(t, http.MethodDelete, ts.URL+location, "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestTusChunkAboveBodyLimit(t *testing.T) {
+	store, err := NewTusFileStore(t.TempDir())
+	assert.NoError(t, err)
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.Tus("/uploads", store)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	size := DEFAULT_MAX_BODY_BYTES + 1024
+	resp := tusRequest(t, http.MethodPost, ts.URL+"/uploads", "", map[string]string{"Upload-Length": strconv.Itoa(size)})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	location := resp.Header.Get("Location")
+
+	chunk := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	resp = tusRequest(t, http.MethodPatch, ts.URL+location, strings.Repeat("x", size), chunk)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, strconv.Itoa(size), resp.Header.Get("Upload-Offset"))
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/loissascha/go-http-server/respond"
+)
+
+// Registers routes (*Server and *Group)
+type Router interface {
+	Route(method string, route string, h func(w http.ResponseWriter, r *http.Request), opts ...RouteOption)
+	routerServer() *Server
+}
+
+func (s *Server) routerServer() *Server {
+	return s
+}
+
+func (g *Group) routerServer() *Server {
+	return g.server
+}
+
+// Error with a HTTP status code. Returned by typed handlers to choose the response status.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) StatusCode() int {
+	return e.Status
+}
+
+// Errors (and outputs of typed handlers) implementing StatusCoder choose their own response status
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Typed route. The request is bound into In (see Bind) and validated (see ValidateStruct),
+// the result is written as JSON. In has to be a struct or a pointer to a struct, pointers
+// are allocated for every request.
+// Errors implementing StatusCoder choose the status code, other errors result in a 500.
+// In and Out are added as export types and documented as request/response schema.
+func Handle[In any, Out any](router Router, method string, route string, h func(ctx context.Context, in In) (Out, error), opts ...RouteOption) {
+	inType := reflect.TypeFor[In]()
+	outType := reflect.TypeFor[Out]()
+
+	s := router.routerServer()
+	structType := inType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	registration := RouteRegistration{
+		Method:   Method(method),
+		Route:    route,
+		Location: registrationLocation(),
+	}
+	if structType.Kind() != reflect.Struct {
+		s.registrationErrors = append(s.registrationErrors, &InvalidInputTypeError{Type: inType, Registration: registration})
+		return
+	}
+	err := checkValidationTags(structType)
+	if err != nil {
+		s.registrationErrors = append(s.registrationErrors, &InvalidValidationTagError{Err: err, Registration: registration})
+		return
+	}
+
+	allOpts := slices.Clone(opts)
+	allOpts = append(allOpts, WithExportType[In](), WithExportType[Out](), withTypedHandler(method, structType, outType))
+
+	router.Route(method, route, func(w http.ResponseWriter, r *http.Request) {
+		var in In
+		target := any(&in)
+		if inType.Kind() == reflect.Pointer {
+			target = reflect.New(structType).Interface()
+			in = target.(In)
+		}
+		err := Bind(r, target)
+		if err != nil {
+			s.writeHandlerError(w, r, err)
+			return
+		}
+		err = ValidateStruct(target)
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			s.translateValidationError(r, validationErr)
+		}
+		if err != nil {
+			s.writeHandlerError(w, r, err)
+			return
+		}
+
+		out, err := h(r.Context(), in)
+		if err != nil {
+			s.writeHandlerError(w, r, err)
+			return
+		}
+
+		status := http.StatusOK
+		if sc, ok := any(out).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+		respond.JSON(w, status, out)
+	}, allOpts...)
+}
+
+// Write err the way typed handlers do: errors implementing StatusCoder choose the status
+// code, validation errors list their fields and other errors result in a 500. Messages of
+// HTTPErrors and of other 4xx errors are sent to the client, 5xx errors only get the status text.
+func (s *Server) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	s.writeHandlerError(w, r, err)
+}
+
+func (s *Server) writeHandlerError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		if s.ProblemDetails {
+			respond.Problem(w, r, validationErr.StatusCode(), respond.ProblemDetails{
+				Title:      s.problemTitle(r, validationErr.StatusCode()),
+				Detail:     "validation failed",
+				Extensions: map[string]any{"errors": validationErr.Errors},
+			})
+			return
+		}
+		respond.JSON(w, validationErr.StatusCode(), map[string]any{
+			"error":  "validation failed",
+			"errors": validationErr.Errors,
+		})
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		status = sc.StatusCode()
+		message = http.StatusText(status)
+		// HTTPError messages are meant for clients, other errors only show their own
+		// message (without the errors wrapping them) for client errors
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			message = httpErr.Message
+		} else if scErr, ok := sc.(error); ok && status < http.StatusInternalServerError {
+			message = scErr.Error()
+		}
+	}
+	if s.ProblemDetails {
+		respond.Problem(w, r, status, respond.ProblemDetails{
+			Title:  s.problemTitle(r, status),
+			Detail: message,
+		})
+		return
+	}
+	respond.JSON(w, status, map[string]string{"error": message})
+}
+
+// documents the in/out types of a typed handler: params from path/query/header tags,
+// the remaining fields as request body and out as response
+func withTypedHandler(method string, in reflect.Type, out reflect.Type) RouteOption {
+	return func(ri *RouteInfo) {
+		for _, param := range structParams(in) {
+			if !slices.ContainsFunc(ri.Params, func(p OpenAPIParam) bool {
+				return p.Name == param.Name && p.In == param.In
+			}) {
+				ri.Params = append(ri.Params, param)
+			}
+		}
+		if method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete && (hasBodyFields(in) || hasFormFields(in)) {
+			ri.RequestType = in
+		}
+		if _, found := ri.ResponseTypes["200"]; !found {
+			ri.ResponseTypes["200"] = out
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const DEFAULT_UPLOAD_MAX_BYTES = 32 << 20
+
+// Limits and storage of multipart uploads (see WithUpload and ParseUpload)
+type UploadConfig struct {
+	// Limit of the whole request body, default 32 MiB
+	MaxBytes int64
+	// Limit of a single file, default MaxBytes
+	MaxFileBytes int64
+	// Allowed sniffed content types, e.g. "image/png" or "image/*". Empty allows every type.
+	AllowedTypes []string
+	// Directory of the default disk storage, default os.TempDir()
+	TempDir string
+	// Where file parts are written to. Defaults to temporary files in TempDir which are removed
+	// after the request (move them to keep them).
+	Storage UploadStorage
+}
+
+// Destination of uploaded files
+type UploadStorage interface {
+	// Store the content of the file and return its location
+	Save(ctx context.Context, file UploadedFile, content io.Reader) (location string, err error)
+	// Remove a stored file (after failed uploads)
+	Remove(ctx context.Context, location string) error
+}
+
+// Stores uploaded files as files in Dir (location is the file path)
+type DiskStorage struct {
+	Dir string
+}
+
+func (d DiskStorage) Save(ctx context.Context, file UploadedFile, content io.Reader) (string, error) {
+	f, err := os.CreateTemp(d.Dir, "upload-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, content)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (d DiskStorage) Remove(ctx context.Context, location string) error {
+	err := os.Remove(location)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// A stored file part of an upload
+type UploadedFile struct {
+	Field    string
+	Filename string
+	// sniffed from the content (not the type sent by the client)
+	ContentType string
+	Size        int64
+	// returned by UploadStorage.Save, the file path for DiskStorage
+	Location string
+}
+
+// Parsed multipart upload
+type Upload struct {
+	Values url.Values
+	Files  []UploadedFile
+}
+
+// The first file of the field
+func (u *Upload) File(field string) (UploadedFile, bool) {
+	for _, file := range u.Files {
+		if file.Field == field {
+			return file, true
+		}
+	}
+	return UploadedFile{}, false
+}
+
+type uploadKey struct{}
+
+type uploadState struct {
+	config    UploadConfig
+	mu        sync.Mutex
+	temporary []string
+}
+
+// Accept multipart uploads on this route: requests above MaxBytes are rejected with 413 and
+// ParseUpload (or Bind) uses the config. The request body is documented as multipart/form-data.
+func WithUpload(config UploadConfig) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.Upload = &config
+	}
+}
+
+// limits the request body and removes temporary upload files after the request
+func (s *Server) uploadMiddleware(config UploadConfig) func(http.Handler) http.Handler {
+	config = uploadConfigDefaults(config)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > config.MaxBytes {
+				s.writeError(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, config.MaxBytes)
+			}
+
+			state := &uploadState{config: config}
+			defer state.removeTemporary(context.WithoutCancel(r.Context()))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), uploadKey{}, state)))
+		})
+	}
+}
+
+func uploadConfigDefaults(config UploadConfig) UploadConfig {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = DEFAULT_UPLOAD_MAX_BYTES
+	}
+	if config.MaxFileBytes <= 0 || config.MaxFileBytes > config.MaxBytes {
+		config.MaxFileBytes = config.MaxBytes
+	}
+	return config
+}
+
+func (state *uploadState) removeTemporary(ctx context.Context) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	storage := DiskStorage{Dir: state.config.TempDir}
+	for _, location := range state.temporary {
+		storage.Remove(ctx, location)
+	}
+	state.temporary = nil
+}
+
+// Parse a multipart/form-data request. Files are streamed into the storage of the route
+// (see WithUpload) without buffering them in memory. Exceeded limits result in a 413, not
+// allowed content types and routes without WithUpload in a 415 HTTPError.
+func ParseUpload(r *http.Request) (*Upload, error) {
+	// only the upload middleware removes temporary files after the request
+	state, ok := r.Context().Value(uploadKey{}).(*uploadState)
+	if !ok {
+		return nil, NewHTTPError(http.StatusUnsupportedMediaType, "multipart uploads are not accepted on this route")
+	}
+	config := state.config
+	storage := config.Storage
+	if storage == nil {
+		storage = DiskStorage{Dir: config.TempDir}
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, &HTTPError{Status: http.StatusBadRequest, Message: "invalid multipart request", Err: err}
+	}
+
+	upload := &Upload{Values: url.Values{}, Files: []UploadedFile{}}
+	fail := func(err error) (*Upload, error) {
+		for _, file := range upload.Files {
+			storage.Remove(r.Context(), file.Location)
+		}
+		return nil, uploadError(err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(part)
+			part.Close()
+			if err != nil {
+				return fail(err)
+			}
+			upload.Values.Add(part.FormName(), string(value))
+			continue
+		}
+
+		file, err := saveUploadPart(r.Context(), part, config, storage)
+		part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		upload.Files = append(upload.Files, file)
+		if config.Storage == nil {
+			state.mu.Lock()
+			state.temporary = append(state.temporary, file.Location)
+			state.mu.Unlock()
+		}
+	}
+	return upload, nil
+}
+
+var errUploadFileTooLarge = errors.New("file too large")
+
+func saveUploadPart(ctx context.Context, part *multipart.Part, config UploadConfig, storage UploadStorage) (UploadedFile, error) {
+	buffered := bufio.NewReaderSize(part, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return UploadedFile{}, err
+	}
+	contentType := http.DetectContentType(head)
+	if !uploadTypeAllowed(contentType, config.AllowedTypes) {
+		return UploadedFile{}, &HTTPError{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("file type %s of %q is not allowed", contentType, part.FileName()),
+		}
+	}
+
+	file := UploadedFile{
+		Field:       part.FormName(),
+		Filename:    part.FileName(),
+		ContentType: contentType,
+	}
+	counter := &uploadCounter{reader: buffered, limit: config.MaxFileBytes}
+	location, err := storage.Save(ctx, file, counter)
+	if counter.exceeded {
+		if err == nil {
+			storage.Remove(ctx, location)
+		}
+		return UploadedFile{}, errUploadFileTooLarge
+	}
+	if err != nil {
+		return UploadedFile{}, err
+	}
+	file.Size = counter.n
+	file.Location = location
+	return file, nil
+}
+
+// counts the read bytes and fails after limit bytes
+type uploadCounter struct {
+	reader   io.Reader
+	limit    int64
+	n        int64
+	exceeded bool
+}
+
+func (c *uploadCounter) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	if c.n > c.limit {
+		c.exceeded = true
+		return n, errUploadFileTooLarge
+	}
+	return n, err
+}
+
+func uploadTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		if strings.EqualFold(pattern, mediaType) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func uploadError(err error) error {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return err
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, errUploadFileTooLarge) {
+		return &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: "upload too large", Err: err}
+	}
+	return &HTTPError{Status: http.StatusBadRequest, Message: "invalid multipart request", Err: err}
+}
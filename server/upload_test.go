@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type uploadInput struct {
+	Title string       `form:"title" validate:"required"`
+	File  UploadedFile `form:"file"`
+}
+
+type uploadOutput struct {
+	Title       string `json:"title"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Content     string `json:"content"`
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func multipartBody(t *testing.T, fields map[string]string, files map[string][]byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for name, value := range fields {
+		assert.NoError(t, mw.WriteField(name, value))
+	}
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		assert.NoError(t, err)
+		_, err = fw.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+	return body, mw.FormDataContentType()
+}
+
+func postMultipart(t *testing.T, url string, body *bytes.Buffer, contentType string) (int, string) {
+	resp, err := http.Post(url, contentType, body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(b))
+}
+
+func TestTypedUpload(t *testing.T) {
+	tempDir := t.TempDir()
+	s, err := NewServer()
+	assert.NoError(t, err)
+	Handle(s, http.MethodPost, "/avatar", func(ctx context.Context, in uploadInput) (uploadOutput, error) {
+		content, err := os.ReadFile(in.File.Location)
+		if err != nil {
+			return uploadOutput{}, err
+		}
+		return uploadOutput{Title: in.Title, ContentType: in.File.ContentType, Size: in.File.Size, Content: string(bytes.TrimPrefix(content, pngHeader))}, nil
+	}, WithUpload(UploadConfig{
+		MaxBytes:     4096,
+		MaxFileBytes: 1024,
+		AllowedTypes: []string{"image/*"},
+		TempDir:      tempDir,
+	}))
+
+	operation := createOperation(&s.Paths["/avatar"][0], "1", newOpenAPISchemas())
+	schema := operation.RequestBody.Content["multipart/form-data"].Schema
+	assert.Equal(t, map[string]any{"type": "string", "format": "binary"}, schema["properties"].(map[string]any)["file"])
+	assert.Contains(t, schema["properties"], "title")
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	png := append(bytes.Clone(pngHeader), []byte("image data")...)
+	body, contentType := multipartBody(t, map[string]string{"title": "me"}, map[string][]byte{"file": png})
+	status, response := postMultipart(t, ts.URL+"/avatar", body, contentType)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, fmt.Sprintf(`{"title":"me","contentType":"image/png","size":%d,"content":"image data"}`, len(png)), response)
+
+	// temporary files are removed after the request
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	body, contentType = multipartBody(t, map[string]string{"title": "me"}, map[string][]byte{"file": []byte("plain text")})
+	status, _ = postMultipart(t, ts.URL+"/avatar", body, contentType)
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+
+	body, contentType = multipartBody(t, map[string]string{"title": "me"}, map[string][]byte{"file": append(bytes.Clone(pngHeader), make([]byte, 2048)...)})
+	status, _ = postMultipart(t, ts.URL+"/avatar", body, contentType)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	body, contentType = multipartBody(t, map[string]string{"title": "me"}, map[string][]byte{"file": make([]byte, 8192)})
+	status, _ = postMultipart(t, ts.URL+"/avatar", body, contentType)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	body, contentType = multipartBody(t, nil, map[string][]byte{"file": png})
+	status, _ = postMultipart(t, ts.URL+"/avatar", body, contentType)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+	entries, err = os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+type memoryStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (m *memoryStorage) Save(ctx context.Context, file UploadedFile, content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	location := fmt.Sprintf("mem/%d", len(m.files))
+	m.files[location] = data
+	return location, nil
+}
+
+func (m *memoryStorage) Remove(ctx context.Context, location string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, location)
+	return nil
+}
+
+func TestParseUploadWithStorage(t *testing.T) {
+	storage := &memoryStorage{files: map[string][]byte{}}
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.POST("/files", func(w http.ResponseWriter, r *http.Request) {
+		upload, err := ParseUpload(r)
+		if err != nil {
+			s.WriteError(w, r, err)
+			return
+		}
+		fmt.Fprintf(w, "%s %d", upload.Values.Get("note"), len(upload.Files))
+	}, WithUpload(UploadConfig{MaxFileBytes: 16, Storage: storage}))
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	body, contentType := multipartBody(t, map[string]string{"note": "hi"}, map[string][]byte{"a": []byte("first"), "b": []byte("second")})
+	status, response := postMultipart(t, ts.URL+"/files", body, contentType)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hi 2", response)
+	assert.Len(t, storage.files, 2)
+
+	// files of failed uploads are removed from the storage
+	body, contentType = multipartBody(t, nil, map[string][]byte{"a": []byte("ok"), "b": make([]byte, 64)})
+	status, _ = postMultipart(t, ts.URL+"/files", body, contentType)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Len(t, storage.files, 2)
+}
+
+func TestMultipartWithoutUploadRoute(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	s, err := NewServer()
+	assert.NoError(t, err)
+	Handle(s, http.MethodPost, "/avatar", func(ctx context.Context, in uploadInput) (string, error) {
+		return in.Title, nil
+	})
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	body, contentType := multipartBody(t, map[string]string{"title": "me"}, map[string][]byte{"file": []byte("data")})
+	status, _ := postMultipart(t, ts.URL+"/avatar", body, contentType)
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A failed validation rule of a single field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Returned by ValidateStruct, lists every failing field
+type ValidationError struct {
+	Errors []FieldError
+	// response status, 422 if not set (request validation uses 400)
+	Status int
+}
+
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Message)
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+func (e *ValidationError) StatusCode() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// default messages, can be translated with the keys validation_<rule> ({field} and {param} are replaced)
+var validationMessages = map[string]string{
+	"required": "{field} is required",
+	"min":      "{field} must be at least {param}",
+	"max":      "{field} must be at most {param}",
+	"len":      "{field} must have a length of {param}",
+	"email":    "{field} must be a valid email address",
+	"url":      "{field} must be a valid URL",
+	"oneof":    "{field} must be one of {param}",
+	// OpenAPI request validation
+	"type":    "{field} must be of type {param}",
+	"format":  "{field} must be a valid {param}",
+	"pattern": "{field} must match {param}",
+	"unknown": "{field} is not allowed",
+	"schema":  "{field} doesn't match any of the allowed schemas",
+	"json":    "{field} must be valid JSON",
+}
+
+// Validate a struct with `validate:"required,min=3,max=10,len=5,email,url,oneof=a b"` tags.
+// min, max and len compare the length of strings (in characters), slices and maps and the
+// value of numbers. Rules are checked for zero values too (0 fails min=18), omitempty skips
+// them for zero values and nil pointers are only checked by required. Nested structs
+// (also in slices and maps) are validated as well. email, url and oneof only apply to
+// strings. Field names are the JSON names of the fields.
+//
+// Returns a *ValidationError if fields are invalid.
+func ValidateStruct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: expected struct, got %T", v)
+	}
+
+	result := &ValidationError{}
+	err := validateStructValue(rv, "", result)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return result
+	}
+	return nil
+}
+
+func validateStructValue(v reflect.Value, prefix string, result *ValidationError) error {
+	t := v.Type()
+	for _, info := range parseStructFields(t) {
+		sf, _ := t.FieldByName(info.GoName)
+		if !info.Exported {
+			continue
+		}
+		name := validationFieldName(sf, info)
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+		field := v.FieldByIndex(sf.Index)
+
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			err := validateField(field, name, tag, result)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := validateNested(field, name, result)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// json name of the field, or the name it is bound from (path, query, header)
+func validationFieldName(sf reflect.StructField, info StructFieldInfo) string {
+	if !info.Ignored {
+		return info.JSONName
+	}
+	for _, location := range []string{"path", "query", "header"} {
+		if name, ok := sf.Tag.Lookup(location); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func validateNested(field reflect.Value, name string, result *ValidationError) error {
+	for field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	switch field.Kind() {
+	case reflect.Struct:
+		if field.Type().PkgPath() == "time" {
+			return nil
+		}
+		return validateStructValue(field, name+".", result)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			err := validateNested(field.Index(i), fmt.Sprintf("%s[%d]", name, i), result)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// sorted keys keep the order of the errors stable
+		keys := field.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, key := range keys {
+			err := validateNested(field.MapIndex(key), fmt.Sprintf("%s[%v]", name, key.Interface()), result)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type validationRule struct {
+	name  string
+	param string
+	limit float64
+}
+
+// rules of a validate tag, errors for unknown rules and invalid parameters
+func parseValidationTag(tag string, name string) ([]validationRule, error) {
+	rules := []validationRule{}
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		r := validationRule{name: ruleName, param: param}
+		switch ruleName {
+		case "":
+			continue
+		case "required", "omitempty", "email", "url":
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("validate: invalid %s parameter %q for %s", ruleName, param, name)
+			}
+			r.limit = limit
+		case "oneof":
+			if strings.TrimSpace(param) == "" {
+				return nil, fmt.Errorf("validate: oneof without values for %s", name)
+			}
+		default:
+			return nil, fmt.Errorf("validate: unknown rule %q for %s", ruleName, name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func hasValidationRule(rules []validationRule, name string) bool {
+	return slices.ContainsFunc(rules, func(r validationRule) bool { return r.name == name })
+}
+
+func validateField(field reflect.Value, name string, tag string, result *ValidationError) error {
+	rules, err := parseValidationTag(tag, name)
+	if err != nil {
+		return err
+	}
+	if field.IsZero() {
+		if hasValidationRule(rules, "required") {
+			result.add(name, "required", "")
+			return nil
+		}
+		if hasValidationRule(rules, "omitempty") || field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface {
+			return nil
+		}
+	}
+	for field.Kind() == reflect.Pointer {
+		field = field.Elem()
+	}
+
+	for _, rule := range rules {
+		if (rule.name == "email" || rule.name == "url" || rule.name == "oneof") && field.Kind() != reflect.String {
+			return fmt.Errorf("validate: %s not supported for %s (%s)", rule.name, name, field.Type())
+		}
+		var ok bool
+		switch rule.name {
+		case "required", "omitempty":
+			continue
+		case "min", "max", "len":
+			size, sizeOk := validationSize(field)
+			if !sizeOk {
+				return fmt.Errorf("validate: %s not supported for %s (%s)", rule.name, name, field.Type())
+			}
+			switch rule.name {
+			case "min":
+				ok = size >= rule.limit
+			case "max":
+				ok = size <= rule.limit
+			case "len":
+				ok = size == rule.limit
+			}
+		case "email":
+			address, err := mail.ParseAddress(field.String())
+			ok = err == nil && address.Address == field.String()
+		case "url":
+			u, err := url.ParseRequestURI(field.String())
+			ok = err == nil && u.Scheme != "" && u.Host != ""
+		case "oneof":
+			ok = slices.Contains(strings.Fields(rule.param), field.String())
+		}
+		if !ok {
+			result.add(name, rule.name, rule.param)
+		}
+	}
+	return nil
+}
+
+// Checks the validate tags of t (and its nested structs) without a value, so invalid tags
+// are found when a route is registered instead of on the first request
+func checkValidationTags(t reflect.Type) error {
+	return checkValidationType(t, "", map[reflect.Type]bool{})
+}
+
+func checkValidationType(t reflect.Type, prefix string, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.PkgPath() == "time" || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	for _, info := range parseStructFields(t) {
+		sf, _ := t.FieldByName(info.GoName)
+		if !info.Exported {
+			continue
+		}
+		name := validationFieldName(sf, info)
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules, err := parseValidationTag(tag, name)
+			if err != nil {
+				return err
+			}
+			fieldType := sf.Type
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			_, sized := validationSize(reflect.Zero(fieldType))
+			for _, rule := range rules {
+				if (rule.name == "min" || rule.name == "max" || rule.name == "len") && !sized {
+					return fmt.Errorf("validate: %s not supported for %s (%s)", rule.name, name, sf.Type)
+				}
+				if (rule.name == "email" || rule.name == "url" || rule.name == "oneof") && fieldType.Kind() != reflect.String {
+					return fmt.Errorf("validate: %s not supported for %s (%s)", rule.name, name, sf.Type)
+				}
+			}
+		}
+
+		err := checkValidationType(sf.Type, name+".", seen)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// length of strings, slices and maps or the value of numbers
+func validationSize(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func (e *ValidationError) add(field string, rule string, param string) {
+	e.Errors = append(e.Errors, FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: formatValidationMessage(validationMessages[rule], field, param),
+	})
+}
+
+func formatValidationMessage(message string, field string, param string) string {
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(message)
+}
+
+// translate the messages with the validation_<rule> keys of the active language
+func (s *Server) translateValidationError(r *http.Request, e *ValidationError) {
+	if !s.TranslationsEnabled {
+		return
+	}
+	for i, fe := range e.Errors {
+		message := s.GetTString(r, "validation_"+fe.Rule)
+		if message != "" {
+			e.Errors[i].Message = formatValidationMessage(message, fe.Field, fe.Param)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validationAddress struct {
+	Street string `json:"street" validate:"required"`
+}
+
+type validationInput struct {
+	Page      int                 `query:"page" json:"-" validate:"omitempty,min=1"`
+	Name      string              `json:"name" validate:"required,min=3,max=5"`
+	Email     string              `json:"email" validate:"omitempty,email"`
+	Website   string              `json:"website,omitempty" validate:"omitempty,url"`
+	Role      string              `json:"role" validate:"omitempty,oneof=admin user"`
+	Code      string              `json:"code" validate:"omitempty,len=4"`
+	Address   *validationAddress  `json:"address"`
+	Addresses []validationAddress `json:"addresses"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	valid := validationInput{Name: "anna", Email: "anna@example.com", Website: "https://example.com", Role: "admin", Code: "ABCD"}
+	assert.NoError(t, ValidateStruct(valid))
+
+	// omitempty skips the rules for empty values
+	assert.NoError(t, ValidateStruct(&validationInput{Name: "ann"}))
+
+	err := ValidateStruct(&validationInput{
+		Page:      -1,
+		Name:      "an",
+		Email:     "Anna <anna@example.com>",
+		Website:   "example.com",
+		Role:      "root",
+		Code:      "ABC",
+		Address:   &validationAddress{},
+		Addresses: []validationAddress{{Street: "Main"}, {}},
+	})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	fields := []string{}
+	for _, fe := range validationErr.Errors {
+		fields = append(fields, fe.Field+":"+fe.Rule)
+	}
+	assert.Equal(t, []string{"page:min", "name:min", "email:email", "website:url", "role:oneof", "code:len", "address.street:required", "addresses[1].street:required"}, fields)
+	assert.Equal(t, "name must be at least 3", validationErr.Errors[1].Message)
+
+	err = ValidateStruct(struct {
+		Name string `validate:"unknown"`
+	}{Name: "x"})
+	assert.Error(t, err)
+	assert.NotErrorAs(t, err, &validationErr)
+}
+
+func TestValidateZeroValues(t *testing.T) {
+	type input struct {
+		Age      int      `json:"age" validate:"min=18"`
+		Role     string   `json:"role" validate:"oneof=admin user"`
+		Nickname *string  `json:"nickname" validate:"min=3"`
+		Tags     []string `json:"tags" validate:"min=1"`
+	}
+	err := ValidateStruct(input{})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	fields := []string{}
+	for _, fe := range validationErr.Errors {
+		fields = append(fields, fe.Field+":"+fe.Rule)
+	}
+	// nil pointers are optional
+	assert.Equal(t, []string{"age:min", "role:oneof", "tags:min"}, fields)
+}
+
+func TestValidateMapValues(t *testing.T) {
+	type input struct {
+		Addresses map[string]validationAddress `json:"addresses"`
+	}
+	err := ValidateStruct(input{Addresses: map[string]validationAddress{"work": {}, "home": {}, "office": {Street: "Main"}}})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	fields := []string{}
+	for _, fe := range validationErr.Errors {
+		fields = append(fields, fe.Field+":"+fe.Rule)
+	}
+	assert.Equal(t, []string{"addresses[home].street:required", "addresses[work].street:required"}, fields)
+}
+
+func TestValidateStringRulesOnOtherKinds(t *testing.T) {
+	err := ValidateStruct(struct {
+		Count int `json:"count" validate:"email"`
+	}{Count: 1})
+	var validationErr *ValidationError
+	assert.Error(t, err)
+	assert.NotErrorAs(t, err, &validationErr)
+	assert.Contains(t, err.Error(), "email not supported for count (int)")
+}
+
+func TestTypedHandlerInvalidValidationTags(t *testing.T) {
+	type nested struct {
+		Count bool `json:"count" validate:"min=1"`
+	}
+	type input struct {
+		Name   string   `json:"name" validate:"max=ten"`
+		Nested []nested `json:"nested"`
+	}
+	type unknownRule struct {
+		Name string `json:"name" validate:"requried"`
+	}
+	s, err := NewServer()
+	assert.NoError(t, err)
+	Handle(s, http.MethodPost, "/a", func(ctx context.Context, in input) (input, error) { return in, nil })
+	Handle(s, http.MethodPost, "/b", func(ctx context.Context, in unknownRule) (unknownRule, error) { return in, nil })
+	Handle(s, http.MethodPost, "/c", func(ctx context.Context, in struct {
+		Items []nested `json:"items"`
+	}) (int, error) {
+		return 0, nil
+	})
+
+	Handle(s, http.MethodPost, "/d", func(ctx context.Context, in struct {
+		Level int `json:"level" validate:"oneof=1 2"`
+	}) (int, error) {
+		return 0, nil
+	})
+	Handle(s, http.MethodPost, "/e", func(ctx context.Context, in struct {
+		Items map[string]struct {
+			Links []int `json:"links" validate:"url"`
+		} `json:"items"`
+	}) (int, error) {
+		return 0, nil
+	})
+
+	errs := s.Validate().(interface{ Unwrap() []error }).Unwrap()
+	assert.Len(t, errs, 5)
+	var tagErr *InvalidValidationTagError
+	assert.ErrorAs(t, errs[0], &tagErr)
+	assert.Equal(t, "/a", tagErr.Registration.Route)
+	assert.Contains(t, errs[0].Error(), `invalid max parameter "ten" for name`)
+	assert.Contains(t, errs[1].Error(), `unknown rule "requried" for name`)
+	assert.Contains(t, errs[2].Error(), "min not supported for items.count")
+	assert.Contains(t, errs[3].Error(), "oneof not supported for level (int)")
+	assert.Contains(t, errs[4].Error(), "url not supported for items.links ([]int)")
+	assert.Empty(t, s.Paths)
+}
+
+func TestTypedHandlerValidationIsTranslated(t *testing.T) {
+	s, err := NewServer(
+		EnableTranslations(),
+		SetDefaultLanguage("en"),
+		AddTranslationFile("en", "en_test.json"),
+		AddTranslationFile("de", "de_test.json"),
+	)
+	assert.NoError(t, err)
+	Handle(s, http.MethodPost, "/users", func(ctx context.Context, in validationInput) (validationInput, error) {
+		return in, nil
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for lang, message := range map[string]string{"de": "name ist erforderlich", "en": "name is required"} {
+		resp, err := http.Post(ts.URL+"/"+lang+"/users", "application/json", bytes.NewBufferString(`{"role":"user"}`))
+		assert.NoError(t, err)
+		var body struct {
+			Errors []FieldError `json:"errors"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, []FieldError{{Field: "name", Rule: "required", Message: message}}, body.Errors)
+	}
+}
@@ -0,0 +1,509 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Configuration of a WebSocket route (see WithWSConfig)
+type WSConfig struct {
+	// Maximum size of a (reassembled) message, default 1 MiB
+	ReadLimit int64
+	// Subprotocols supported by the server in order of preference
+	Subprotocols []string
+	// Checks the Origin of the handshake. Default: the CORS policy of the route or server.
+	CheckOrigin func(r *http.Request) bool
+	// Send pings in this interval (0 disables pings)
+	PingInterval time.Duration
+	// Maximum duration of a single write, default 10s
+	WriteTimeout time.Duration
+}
+
+// Configure a WebSocket route
+func WithWSConfig(config WSConfig) RouteOption {
+	return func(ri *RouteInfo) {
+		ri.WebSocket = &config
+	}
+}
+
+type WSMessageType int
+
+const (
+	WS_TEXT   WSMessageType = 1
+	WS_BINARY WSMessageType = 2
+)
+
+// RFC 6455 close codes
+const (
+	WS_CLOSE_NORMAL           = 1000
+	WS_CLOSE_GOING_AWAY       = 1001
+	WS_CLOSE_PROTOCOL_ERROR   = 1002
+	WS_CLOSE_UNSUPPORTED_DATA = 1003
+	WS_CLOSE_NO_STATUS        = 1005
+	WS_CLOSE_INVALID_PAYLOAD  = 1007
+	WS_CLOSE_POLICY_VIOLATION = 1008
+	WS_CLOSE_MESSAGE_TOO_BIG  = 1009
+	WS_CLOSE_INTERNAL_ERROR   = 1011
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Returned by ReadMessage when the connection was closed with a close frame
+type WSCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WSCloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// A WebSocket connection. ReadMessage must not be called concurrently, writes are safe
+// for concurrent use.
+type WSConn struct {
+	conn         net.Conn
+	br           *bufio.Reader
+	writeMu      sync.Mutex
+	closeOnce    sync.Once
+	closeSent    bool
+	readLimit    int64
+	writeTimeout time.Duration
+	subprotocol  string
+	request      *http.Request
+	done         chan struct{}
+	doneOnce     sync.Once
+}
+
+// WebSocket route (RFC 6455). The handshake passes through the middleware chain of the
+// route, the connection is closed when the handler returns. On Shutdown open connections
+// get a "going away" close frame.
+func (s *Server) WS(route string, h func(ctx context.Context, c *WSConn), opts ...RouteOption) {
+	info := getRouteInfos(opts...)
+	config := WSConfig{}
+	if info.WebSocket != nil {
+		config = *info.WebSocket
+	}
+	if config.ReadLimit <= 0 {
+		config.ReadLimit = 1 << 20
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	checkOrigin := config.CheckOrigin
+	if checkOrigin == nil {
+		policy := s.corsPolicy
+		if info.CORS != nil {
+			policy = newCORSPolicy(*info.CORS)
+		}
+		checkOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || policy.originAllowed(origin)
+		}
+	}
+
+	s.GET(route, func(w http.ResponseWriter, r *http.Request) {
+		if !checkOrigin(r) {
+			s.writeError(w, r, http.StatusForbidden, "WebSocket: Origin not allowed")
+			return
+		}
+		c, err := upgradeWebSocket(w, r, config)
+		if err != nil {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				s.writeError(w, r, httpErr.Status, httpErr.Message)
+			}
+			return
+		}
+
+		s.trackWSConn(c, true)
+		defer s.trackWSConn(c, false)
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		for server := s; server != nil; server = server.parent {
+			stop := context.AfterFunc(server.streamsContext(), cancel)
+			defer stop()
+		}
+		go func() {
+			select {
+			case <-c.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		if config.PingInterval > 0 {
+			go c.pingLoop(ctx, config.PingInterval)
+		}
+
+		h(ctx, c)
+		c.Close(WS_CLOSE_NORMAL, "")
+	}, opts...)
+}
+
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, config WSConfig) (*WSConn, error) {
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		return nil, NewHTTPError(http.StatusUpgradeRequired, "WebSocket: upgrade required")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, NewHTTPError(http.StatusUpgradeRequired, "WebSocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, NewHTTPError(http.StatusBadRequest, "WebSocket: invalid Sec-WebSocket-Key")
+	}
+
+	subprotocol := ""
+	requested := []string{}
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for protocol := range strings.SplitSeq(value, ",") {
+			requested = append(requested, strings.TrimSpace(protocol))
+		}
+	}
+	for _, protocol := range config.Subprotocols {
+		if slices.Contains(requested, protocol) {
+			subprotocol = protocol
+			break
+		}
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, NewHTTPError(http.StatusInternalServerError, "WebSocket: connection can't be hijacked")
+	}
+	// the server timeouts don't apply to hijacked connections
+	conn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	b.WriteString("Upgrade: websocket\r\n")
+	b.WriteString("Connection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	conn.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+	_, err = conn.Write([]byte(b.String()))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+
+	return &WSConn{
+		conn:         conn,
+		br:           brw.Reader,
+		readLimit:    config.ReadLimit,
+		writeTimeout: config.WriteTimeout,
+		subprotocol:  subprotocol,
+		request:      r,
+		done:         make(chan struct{}),
+	}, nil
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The negotiated subprotocol (empty if none)
+func (c *WSConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// The request of the handshake
+func (c *WSConn) Request() *http.Request {
+	return c.request
+}
+
+// Read the next message. Fragmented messages are reassembled, pings are answered.
+// Returns a *WSCloseError when the peer closed the connection.
+func (c *WSConn) ReadMessage() (WSMessageType, []byte, error) {
+	var messageType WSMessageType
+	message := []byte{}
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			err = c.writeFrame(wsOpPong, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			closeErr := &WSCloseError{Code: WS_CLOSE_NO_STATUS}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			code := closeErr.Code
+			if code == WS_CLOSE_NO_STATUS {
+				code = WS_CLOSE_NORMAL
+			}
+			c.Close(code, "")
+			return 0, nil, closeErr
+		case wsOpText, wsOpBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(WS_CLOSE_PROTOCOL_ERROR, "new message inside fragmented message")
+			}
+			messageType = WSMessageType(opcode)
+		case wsOpContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(WS_CLOSE_PROTOCOL_ERROR, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(WS_CLOSE_PROTOCOL_ERROR, "unknown opcode")
+		}
+
+		if int64(len(message)+len(payload)) > c.readLimit {
+			return 0, nil, c.fail(WS_CLOSE_MESSAGE_TOO_BIG, "message too big")
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if messageType == WS_TEXT && !utf8.Valid(message) {
+				return 0, nil, c.fail(WS_CLOSE_INVALID_PAYLOAD, "invalid UTF-8")
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// Read the next message and decode it as JSON
+func (c *WSConn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *WSConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	_, err = io.ReadFull(c.br, header)
+	if err != nil {
+		c.markDone()
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(WS_CLOSE_PROTOCOL_ERROR, "reserved bits set")
+	}
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if !masked {
+		return false, 0, nil, c.fail(WS_CLOSE_PROTOCOL_ERROR, "client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(c.br, ext); err != nil {
+			c.markDone()
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(c.br, ext); err != nil {
+			c.markDone()
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if opcode >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(WS_CLOSE_PROTOCOL_ERROR, "invalid control frame")
+	}
+	if length > uint64(c.readLimit) {
+		return false, 0, nil, c.fail(WS_CLOSE_MESSAGE_TOO_BIG, "message too big")
+	}
+
+	mask := make([]byte, 4)
+	if _, err = io.ReadFull(c.br, mask); err != nil {
+		c.markDone()
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		c.markDone()
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// Write a message
+func (c *WSConn) WriteMessage(messageType WSMessageType, data []byte) error {
+	if messageType != WS_TEXT && messageType != WS_BINARY {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// Write v as JSON text message
+func (c *WSConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(WS_TEXT, data)
+}
+
+// Send a ping (the pong is handled by ReadMessage)
+func (c *WSConn) Ping(data []byte) error {
+	return c.writeFrame(wsOpPing, data)
+}
+
+func (c *WSConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *WSConn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Send a close frame and close the connection (only the first call has an effect)
+func (c *WSConn) Close(code int, reason string) error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.writeMu.Lock()
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		if len(reason) > 123 {
+			reason = reason[:123]
+		}
+		payload = append(payload, reason...)
+		c.writeFrameLocked(wsOpClose, payload)
+		c.closeSent = true
+		c.writeMu.Unlock()
+
+		c.markDone()
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *WSConn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return &WSCloseError{Code: code, Reason: reason}
+}
+
+func (c *WSConn) markDone() {
+	c.doneOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *WSConn) pingLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.Ping(nil) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) trackWSConn(c *WSConn, add bool) {
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	if add {
+		if s.wsConns == nil {
+			s.wsConns = map[*WSConn]struct{}{}
+		}
+		s.wsConns[c] = struct{}{}
+		return
+	}
+	delete(s.wsConns, c)
+}
+
+// send a close frame to all open WebSocket connections, including the ones of mounted servers
+func (s *Server) closeWSConns(code int, reason string) {
+	s.wsMu.Lock()
+	conns := []*WSConn{}
+	for c := range s.wsConns {
+		conns = append(conns, c)
+	}
+	s.wsMu.Unlock()
+
+	for _, c := range conns {
+		c.Close(code, reason)
+	}
+	for _, m := range s.mounts {
+		if child, ok := m.handler.(*Server); ok {
+			child.closeWSConns(code, reason)
+		}
+	}
+}
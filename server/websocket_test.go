@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type wsTestClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, addr string, path string, origin string) (*wsTestClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Protocol", "chat, json")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	assert.NoError(t, req.Write(conn))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	assert.NoError(t, err)
+	return &wsTestClient{conn: conn, br: br}, resp
+}
+
+func (c *wsTestClient) writeFrame(fin bool, opcode byte, payload []byte) {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+func (c *wsTestClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.br, header)
+	assert.NoError(t, err)
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		ext := make([]byte, 2)
+		io.ReadFull(c.br, ext)
+		length = int(binary.BigEndian.Uint16(ext))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	assert.NoError(t, err)
+	return header[0] & 0x0F, payload
+}
+
+func TestWebSocket(t *testing.T) {
+	s, err := NewServer(WithCORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	assert.NoError(t, err)
+	s.GETI("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	middlewareCalled := false
+	s.WS("/ws", func(ctx context.Context, c *WSConn) {
+		for {
+			messageType, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			c.WriteMessage(messageType, []byte(c.Subprotocol()+":"+string(data)))
+		}
+	}, WithWSConfig(WSConfig{ReadLimit: 16, Subprotocols: []string{"json", "chat"}}), WithMiddlewares(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewareCalled = true
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	addr := getFreeAddr(t)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(addr)
+	}()
+	waitForServer(t, addr, "http", "/health", nil)
+
+	_, resp := dialWebSocket(t, addr, "/ws", "https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = http.Get("http://" + addr + "/ws")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+
+	c, resp := dialWebSocket(t, addr, "/ws", "https://app.example.com")
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "json", resp.Header.Get("Sec-WebSocket-Protocol"))
+	assert.True(t, middlewareCalled)
+
+	// fragmented message with a ping in between
+	c.writeFrame(false, wsOpText, []byte("hel"))
+	c.writeFrame(true, wsOpPing, []byte("p"))
+	c.writeFrame(true, wsOpContinuation, []byte("lo"))
+	opcode, payload := c.readFrame(t)
+	assert.Equal(t, byte(wsOpPong), opcode)
+	assert.Equal(t, "p", string(payload))
+	opcode, payload = c.readFrame(t)
+	assert.Equal(t, byte(wsOpText), opcode)
+	assert.Equal(t, "json:hello", string(payload))
+
+	// read limit
+	c.writeFrame(true, wsOpBinary, []byte(strings.Repeat("x", 17)))
+	opcode, payload = c.readFrame(t)
+	assert.Equal(t, byte(wsOpClose), opcode)
+	assert.Equal(t, WS_CLOSE_MESSAGE_TOO_BIG, int(binary.BigEndian.Uint16(payload)))
+
+	// shutdown sends going away to open connections
+	c, _ = dialWebSocket(t, addr, "/ws", "")
+	c.writeFrame(true, wsOpText, []byte("hi"))
+	c.readFrame(t)
+	assert.NoError(t, s.Shutdown(context.Background()))
+	opcode, payload = c.readFrame(t)
+	assert.Equal(t, byte(wsOpClose), opcode)
+	assert.Equal(t, WS_CLOSE_GOING_AWAY, int(binary.BigEndian.Uint16(payload)))
+	assert.NoError(t, <-errCh)
+}
+
+func TestWebSocketMountedShutdown(t *testing.T) {
+	s, err := NewServer()
+	assert.NoError(t, err)
+	s.GETI("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	child, err := NewServer()
+	assert.NoError(t, err)
+	done := make(chan struct{})
+	child.WS("/ws", func(ctx context.Context, c *WSConn) {
+		<-ctx.Done()
+		close(done)
+	})
+	s.Mount("/api", child)
+
+	addr := getFreeAddr(t)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(addr)
+	}()
+	waitForServer(t, addr, "http", "/health", nil)
+
+	c, resp := dialWebSocket(t, addr, "/api/ws", "")
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(shutdownCtx))
+	opcode, payload := c.readFrame(t)
+	assert.Equal(t, byte(wsOpClose), opcode)
+	assert.Equal(t, WS_CLOSE_GOING_AWAY, int(binary.BigEndian.Uint16(payload)))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("context of the mounted WebSocket wasn't cancelled")
+	}
+	assert.NoError(t, <-errCh)
+}